@@ -0,0 +1,233 @@
+// Package card renders quotes as shareable image cards
+package card
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	DEFAULT_WIDTH  = 1200
+	DEFAULT_HEIGHT = 630
+	DEFAULT_THEME  = "light"
+
+	minFontSize = 12
+)
+
+type Theme struct {
+	Background color.RGBA
+	Foreground color.RGBA
+	Accent     color.RGBA
+	Muted      color.RGBA
+}
+
+// Themes follow the colours of the GoQu terminal recordings
+var Themes = map[string]Theme{
+	"light": {
+		Background: color.RGBA{0xf9, 0xf9, 0xf4, 0xff},
+		Foreground: color.RGBA{0x23, 0x26, 0x28, 0xff},
+		Accent:     color.RGBA{0x75, 0xdf, 0xf2, 0xff},
+		Muted:      color.RGBA{0x62, 0x65, 0x66, 0xff},
+	},
+	"dark": {
+		Background: color.RGBA{0x23, 0x26, 0x28, 0xff},
+		Foreground: color.RGBA{0xf9, 0xf9, 0xf4, 0xff},
+		Accent:     color.RGBA{0xae, 0x89, 0xfe, 0xff},
+		Muted:      color.RGBA{0xaf, 0xaf, 0xaf, 0xff},
+	},
+	"goku": {
+		Background: color.RGBA{0xff, 0xa7, 0x27, 0xff},
+		Foreground: color.RGBA{0x23, 0x26, 0x28, 0xff},
+		Accent:     color.RGBA{0x1f, 0x4e, 0xa8, 0xff},
+		Muted:      color.RGBA{0x4a, 0x3a, 0x20, 0xff},
+	},
+}
+
+type Options struct {
+	Width  int
+	Height int
+	Theme  Theme
+	// Background overrides the theme background when set
+	Background *color.RGBA
+	HideAuthor bool
+}
+
+var (
+	regularFont *opentype.Font
+	italicFont  *opentype.Font
+)
+
+func init() {
+	var err error
+	if regularFont, err = opentype.Parse(goregular.TTF); err != nil {
+		panic(err)
+	}
+	if italicFont, err = opentype.Parse(goitalic.TTF); err != nil {
+		panic(err)
+	}
+}
+
+// ThemeNames lists the available themes
+func ThemeNames() []string {
+	var names []string
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseColor reads a #rgb or #rrggbb colour
+func ParseColor(s string) (*color.RGBA, error) {
+	c := &color.RGBA{A: 0xff}
+	var err error
+	switch len(s) {
+	case 4:
+		_, err = fmt.Sscanf(s, "#%1x%1x%1x", &c.R, &c.G, &c.B)
+		c.R, c.G, c.B = c.R*17, c.G*17, c.B*17
+	case 7:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	default:
+		err = fmt.Errorf("expected #rgb or #rrggbb")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid colour %q: %v", s, err)
+	}
+	return c, nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.Width <= 0 {
+		o.Width = DEFAULT_WIDTH
+	}
+	if o.Height <= 0 {
+		o.Height = DEFAULT_HEIGHT
+	}
+	if o.Theme == (Theme{}) {
+		o.Theme = Themes[DEFAULT_THEME]
+	}
+	if o.Background != nil {
+		o.Theme.Background = *o.Background
+	}
+	return o
+}
+
+type line struct {
+	text string
+	x, y int
+}
+
+// layout is shared by the PNG and SVG renderers so both wrap the same way
+type layout struct {
+	opts       Options
+	padding    int
+	textSize   float64
+	authorSize float64
+	lines      []line
+	author     *line
+}
+
+func newLayout(q *source.Quote, opts Options) (*layout, error) {
+	opts = opts.withDefaults()
+	l := &layout{opts: opts, padding: opts.Width / 12}
+	maxWidth := opts.Width - 2*l.padding
+	maxHeight := opts.Height - 2*l.padding
+
+	attribution := q.Attribution()
+	if opts.HideAuthor {
+		attribution = ""
+	}
+	text := "“" + strings.TrimSpace(q.Text) + "”"
+
+	// shrink the text until it fits the card
+	for size := float64(opts.Height) / 7; ; size -= 2 {
+		if size < minFontSize {
+			return nil, fmt.Errorf("quote is too long for a %dx%d card", opts.Width, opts.Height)
+		}
+		face, err := newFace(regularFont, size)
+		if err != nil {
+			return nil, err
+		}
+		wrapped := wrap(face, text, maxWidth)
+		lineHeight := int(size * 1.35)
+		height := len(wrapped) * lineHeight
+
+		authorSize := size * 0.6
+		if authorSize > 36 {
+			authorSize = 36
+		}
+		if authorSize < minFontSize {
+			authorSize = minFontSize
+		}
+		authorGap := 0
+		if attribution != "" {
+			authorGap = int(authorSize * 2.2)
+		}
+		tooWide := false
+		for _, w := range wrapped {
+			if font.MeasureString(face, w) > fixed.I(maxWidth) {
+				tooWide = true
+			}
+		}
+		face.Close()
+		if attribution != "" {
+			authorFace, err := newFace(italicFont, authorSize)
+			if err != nil {
+				return nil, err
+			}
+			tooWide = tooWide || font.MeasureString(authorFace, "— "+attribution) > fixed.I(maxWidth)
+			authorFace.Close()
+		}
+		if tooWide || height+authorGap > maxHeight {
+			continue
+		}
+
+		l.textSize, l.authorSize = size, authorSize
+		top := l.padding + (maxHeight-height-authorGap)/2
+		for i, w := range wrapped {
+			l.lines = append(l.lines, line{text: w, x: l.padding, y: top + (i+1)*lineHeight - int(size*0.35)})
+		}
+		if attribution != "" {
+			l.author = &line{text: "— " + attribution, x: l.padding, y: top + height + authorGap - int(authorSize*0.4)}
+		}
+		return l, nil
+	}
+}
+
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// wrap breaks text into lines no wider than maxWidth pixels
+func wrap(face font.Face, text string, maxWidth int) []string {
+	var lines []string
+	limit := fixed.I(maxWidth)
+	for _, paragraph := range strings.Split(text, "\n") {
+		current := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if current != "" {
+				candidate = current + " " + word
+			}
+			if current != "" && font.MeasureString(face, candidate) > limit {
+				lines = append(lines, current)
+				candidate = word
+			}
+			current = candidate
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+	}
+	return lines
+}
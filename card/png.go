@@ -0,0 +1,53 @@
+package card
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+// PNG renders a quote card as a PNG image
+func PNG(w io.Writer, q *source.Quote, opts Options) error {
+	l, err := newLayout(q, opts)
+	if err != nil {
+		return err
+	}
+	theme := l.opts.Theme
+
+	img := image.NewRGBA(image.Rect(0, 0, l.opts.Width, l.opts.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(theme.Background), image.Point{}, draw.Src)
+	bar := image.Rect(l.padding/2, l.padding, l.padding/2+l.padding/8, l.opts.Height-l.padding)
+	draw.Draw(img, bar, image.NewUniform(theme.Accent), image.Point{}, draw.Src)
+
+	if err := drawLines(img, regularFont, l.textSize, theme.Foreground, l.lines); err != nil {
+		return err
+	}
+	if l.author != nil {
+		if err := drawLines(img, italicFont, l.authorSize, theme.Muted, []line{*l.author}); err != nil {
+			return err
+		}
+	}
+	return png.Encode(w, img)
+}
+
+func drawLines(img draw.Image, f *opentype.Font, size float64, c color.Color, lines []line) error {
+	face, err := newFace(f, size)
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face}
+	for _, l := range lines {
+		d.Dot = fixed.P(l.x, l.y)
+		d.DrawString(l.text)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package card
+
+import (
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"io"
+	"text/template"
+
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+// the fonts are embedded so the card looks the same everywhere
+var svgTemplate = template.Must(template.New("svg").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+<defs><style>
+@font-face { font-family: "GoQu Regular"; src: url({{.RegularFont}}) format("truetype"); }
+@font-face { font-family: "GoQu Italic"; src: url({{.ItalicFont}}) format("truetype"); }
+</style></defs>
+<rect width="100%" height="100%" fill="{{.Background}}"/>
+<rect x="{{.BarX}}" y="{{.Padding}}" width="{{.BarWidth}}" height="{{.BarHeight}}" fill="{{.Accent}}"/>
+<g font-family="GoQu Regular" font-size="{{.TextSize}}" fill="{{.Foreground}}">
+{{range .Lines}}<text x="{{.X}}" y="{{.Y}}" xml:space="preserve">{{html .Text}}</text>
+{{end}}</g>
+{{with .Author}}<text x="{{.X}}" y="{{.Y}}" font-family="GoQu Italic" font-size="{{$.AuthorSize}}" fill="{{$.Muted}}">{{html .Text}}</text>
+{{end}}</svg>
+`))
+
+type svgLine struct {
+	X, Y int
+	Text string
+}
+
+// SVG renders a quote card as a standalone SVG document
+func SVG(w io.Writer, q *source.Quote, opts Options) error {
+	l, err := newLayout(q, opts)
+	if err != nil {
+		return err
+	}
+	theme := l.opts.Theme
+
+	data := map[string]interface{}{
+		"Width":       l.opts.Width,
+		"Height":      l.opts.Height,
+		"Padding":     l.padding,
+		"BarX":        l.padding / 2,
+		"BarWidth":    l.padding / 8,
+		"BarHeight":   l.opts.Height - 2*l.padding,
+		"Background":  hex(theme.Background),
+		"Foreground":  hex(theme.Foreground),
+		"Accent":      hex(theme.Accent),
+		"Muted":       hex(theme.Muted),
+		"TextSize":    l.textSize,
+		"AuthorSize":  l.authorSize,
+		"RegularFont": fontURL(goregular.TTF),
+		"ItalicFont":  fontURL(goitalic.TTF),
+	}
+	var lines []svgLine
+	for _, ln := range l.lines {
+		lines = append(lines, svgLine{X: ln.x, Y: ln.y, Text: ln.text})
+	}
+	data["Lines"] = lines
+	if l.author != nil {
+		data["Author"] = svgLine{X: l.author.x, Y: l.author.y, Text: l.author.text}
+	}
+	return svgTemplate.Execute(w, data)
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func fontURL(ttf []byte) string {
+	return "data:font/ttf;base64," + base64.StdEncoding.EncodeToString(ttf)
+}
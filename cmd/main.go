@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
 
 	"github.com/custompointofview/goqu/interfaces"
 )
@@ -10,6 +14,26 @@ func main() {
 	// create application context
 	ctx := context.Background()
 
+	args, err := interfaces.ParseFlags(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	// run a single command if one was given
+	if len(args) > 0 {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+		if err := interfaces.RunCommand(ctx, args[0], args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "goqu:", err)
+			stop()
+			os.Exit(1)
+		}
+		return
+	}
+
 	// run
 	t := interfaces.NewTerm()
 	t.Run(ctx)
@@ -0,0 +1,189 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	htmltemplate "html/template"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+const epubStyle = `
+body { font-family: serif; line-height: 1.4; }
+blockquote { margin: 1.2em 0.5em; }
+blockquote p { margin: 0.3em 0; white-space: pre-line; }
+p.attribution { text-align: right; font-style: italic; }
+`
+
+var epubFuncs = template.FuncMap{
+	"xml": xmlEscape,
+	"inc": func(i int) int { return i + 1 },
+}
+
+type epubFile struct {
+	name  string
+	write func(io.Writer) error
+}
+
+var epubPackage = template.Must(template.New("opf").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="book-id">{{.ID}}</dc:identifier>
+<dc:title>{{xml .Title}}</dc:title>
+<dc:language>{{.Language}}</dc:language>
+{{range .Providers}}<dc:contributor>{{xml .}}</dc:contributor>
+{{end}}<meta property="dcterms:modified">{{.Modified}}</meta>
+</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="style" href="style.css" media-type="text/css"/>
+{{range .Groups}}<item id="ch-{{.Anchor}}" href="{{.Anchor}}.xhtml" media-type="application/xhtml+xml"/>
+{{end}}</manifest>
+<spine toc="ncx">
+<itemref idref="nav"/>
+{{range .Groups}}<itemref idref="ch-{{.Anchor}}"/>
+{{end}}</spine>
+</package>
+`))
+
+var epubNCX = template.Must(template.New("ncx").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+<head><meta name="dtb:uid" content="{{.ID}}"/></head>
+<docTitle><text>{{xml .Title}}</text></docTitle>
+<navMap>
+{{range $i, $g := .Groups}}<navPoint id="nav-{{$g.Anchor}}" playOrder="{{inc $i}}"><navLabel><text>{{xml $g.Name}}</text></navLabel><content src="{{$g.Anchor}}.xhtml"/></navPoint>
+{{end}}</navMap>
+</ncx>
+`))
+
+// html/template escapes the XML declaration, writeXHTML adds it
+const xmlDeclaration = `<?xml version="1.0" encoding="UTF-8"?>
+`
+
+var epubNav = htmltemplate.Must(htmltemplate.New("nav").Parse(`<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>{{.Title}}</title><link rel="stylesheet" href="style.css"/></head>
+<body>
+<h1>{{.Title}}</h1>
+<nav epub:type="toc" id="toc">
+<h2>Contents</h2>
+<ol>
+{{range .Groups}}<li><a href="{{.Anchor}}.xhtml">{{.Name}}</a></li>
+{{end}}</ol>
+</nav>
+{{if .Providers}}<p>Quotes provided by {{.ProviderList}}.</p>
+{{end}}</body>
+</html>
+`))
+
+var epubChapter = htmltemplate.Must(htmltemplate.New("chapter").Parse(`<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head><title>{{.Name}}</title><link rel="stylesheet" href="style.css"/></head>
+<body>
+<h2>{{.Name}}</h2>
+{{range .Quotes}}<blockquote>
+<p>{{.Text}}</p>
+{{if .Attribution}}<p class="attribution">— {{.Attribution}}</p>{{end}}
+</blockquote>
+{{end}}</body>
+</html>
+`))
+
+// EPUB writes quotes as an EPUB 3 book with one chapter per group
+func EPUB(w io.Writer, quotes []*source.Quote, opts Options) error {
+	groups, err := GroupQuotes(quotes, opts.GroupBy)
+	if err != nil {
+		return err
+	}
+	providers := Providers(quotes)
+	data := map[string]interface{}{
+		"ID":           epubID(quotes),
+		"Title":        opts.title(),
+		"Language":     opts.language(quotes),
+		"Modified":     time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Groups":       groups,
+		"Providers":    providers,
+		"ProviderList": strings.Join(providers, ", "),
+	}
+
+	zw := zip.NewWriter(w)
+	// the mimetype must come first and uncompressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	files := []epubFile{
+		{"META-INF/container.xml", writeString(epubContainer)},
+		{"OEBPS/style.css", writeString(epubStyle)},
+		{"OEBPS/content.opf", func(w io.Writer) error { return epubPackage.Execute(w, data) }},
+		{"OEBPS/toc.ncx", func(w io.Writer) error { return epubNCX.Execute(w, data) }},
+		{"OEBPS/nav.xhtml", writeXHTML(epubNav, data)},
+	}
+	for _, g := range groups {
+		files = append(files, epubFile{"OEBPS/" + g.Anchor + ".xhtml", writeXHTML(epubChapter, g)})
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if err := f.write(fw); err != nil {
+			return fmt.Errorf("%s: %v", f.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func writeXHTML(t *htmltemplate.Template, data interface{}) func(io.Writer) error {
+	return func(w io.Writer) error {
+		if _, err := io.WriteString(w, xmlDeclaration); err != nil {
+			return err
+		}
+		return t.Execute(w, data)
+	}
+}
+
+// epubID derives a stable urn:uuid from the quote IDs
+func epubID(quotes []*source.Quote) string {
+	h := sha1.New()
+	for _, q := range quotes {
+		io.WriteString(h, q.ID+"\n")
+	}
+	sum := h.Sum(nil)
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	template.HTMLEscape(&b, []byte(s))
+	return b.String()
+}
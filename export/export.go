@@ -0,0 +1,128 @@
+// Package export writes quote collections into documents
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	GROUP_NONE   = ""
+	GROUP_AUTHOR = "author"
+	GROUP_GENRE  = "genre"
+
+	DEFAULT_TITLE    = "Quotes"
+	UNKNOWN_GROUP    = "Unknown"
+	DEFAULT_LANGUAGE = "en"
+)
+
+type Options struct {
+	Title string
+	// GroupBy is one of GROUP_NONE, GROUP_AUTHOR or GROUP_GENRE
+	GroupBy string
+	// Language is the BCP 47 tag of the document, taken from the quotes when empty
+	Language string
+}
+
+// Group is a chapter of an exported document
+type Group struct {
+	Name   string
+	Anchor string
+	Quotes []*source.Quote
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+func (o Options) title() string {
+	if o.Title == "" {
+		return DEFAULT_TITLE
+	}
+	return o.Title
+}
+
+// language returns the configured language, or the first one of the quotes
+func (o Options) language(quotes []*source.Quote) string {
+	if o.Language != "" {
+		return o.Language
+	}
+	for _, q := range quotes {
+		if q.Language != "" {
+			return q.Language
+		}
+	}
+	return DEFAULT_LANGUAGE
+}
+
+// GroupQuotes splits quotes into groups sorted by name.
+// Quotes keep their order inside a group.
+func GroupQuotes(quotes []*source.Quote, groupBy string) ([]*Group, error) {
+	key := func(q *source.Quote) string { return DEFAULT_TITLE }
+	switch groupBy {
+	case GROUP_NONE:
+	case GROUP_AUTHOR:
+		key = func(q *source.Quote) string { return q.Author }
+	case GROUP_GENRE:
+		key = func(q *source.Quote) string { return q.Genre }
+	default:
+		return nil, fmt.Errorf("unknown grouping: %s", groupBy)
+	}
+
+	byName := map[string]*Group{}
+	var groups []*Group
+	for _, q := range quotes {
+		name := strings.TrimSpace(key(q))
+		if name == "" {
+			name = UNKNOWN_GROUP
+		}
+		g, ok := byName[name]
+		if !ok {
+			g = &Group{Name: name}
+			byName[name] = g
+			groups = append(groups, g)
+		}
+		g.Quotes = append(g.Quotes, q)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Name) < strings.ToLower(groups[j].Name)
+	})
+
+	// anchors also name the EPUB chapters, next to nav.xhtml, toc.ncx,
+	// style.css and content.opf, whose manifest ids are ch-<anchor>
+	used := map[string]int{"nav": 1, "toc": 1, "style": 1, "content": 1}
+	for i, g := range groups {
+		anchor := strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(g.Name), "-"), "-")
+		// anchors double as XML ids, which can not start with a digit
+		if anchor == "" || (anchor[0] >= '0' && anchor[0] <= '9') {
+			anchor = fmt.Sprintf("group-%d", i+1)
+		}
+		if strings.HasPrefix(anchor, "ch-") {
+			anchor = "group-" + anchor
+		}
+		if n := used[anchor]; n > 0 {
+			used[anchor]++
+			anchor = fmt.Sprintf("%s-%d", anchor, n+1)
+		} else {
+			used[anchor] = 1
+		}
+		g.Anchor = anchor
+	}
+	return groups, nil
+}
+
+// Providers lists where the quotes come from, for the attribution notice
+func Providers(quotes []*source.Quote) []string {
+	seen := map[string]bool{}
+	var providers []string
+	for _, q := range quotes {
+		if q.Provider != "" && !seen[q.Provider] {
+			seen[q.Provider] = true
+			providers = append(providers, q.Provider)
+		}
+	}
+	sort.Strings(providers)
+	return providers
+}
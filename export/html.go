@@ -0,0 +1,62 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const htmlStyle = `
+body { font-family: Georgia, serif; max-width: 46em; margin: 2em auto; padding: 0 1em; color: #222; line-height: 1.5; }
+h1, h2 { font-family: Helvetica, Arial, sans-serif; }
+nav ul { columns: 2; }
+blockquote { margin: 1.5em 0; padding: 0.5em 1em; border-left: 4px solid #75dff2; background: #f7f7f5; }
+blockquote p { margin: 0.3em 0; white-space: pre-line; }
+blockquote footer { color: #666; font-style: italic; }
+.colophon { margin-top: 3em; font-size: 0.85em; color: #666; }
+`
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if gt (len .Groups) 1}}<nav>
+<h2>Contents</h2>
+<ul>
+{{range .Groups}}<li><a href="#{{.Anchor}}">{{.Name}}</a> ({{len .Quotes}})</li>
+{{end}}</ul>
+</nav>
+{{end}}{{$grouped := gt (len .Groups) 1}}{{range .Groups}}<section id="{{.Anchor}}">
+{{if $grouped}}<h2>{{.Name}}</h2>
+{{end}}{{range .Quotes}}<blockquote>
+<p>{{.Text}}</p>
+{{if .Attribution}}<footer>— {{if .URL}}<a href="{{.URL}}">{{.Attribution}}</a>{{else}}{{.Attribution}}{{end}}</footer>{{end}}
+</blockquote>
+{{end}}</section>
+{{end}}{{if .Providers}}<p class="colophon">Quotes provided by {{.Providers}}.</p>
+{{end}}</body>
+</html>
+`))
+
+// HTML writes quotes as a single self-contained HTML page
+func HTML(w io.Writer, quotes []*source.Quote, opts Options) error {
+	groups, err := GroupQuotes(quotes, opts.GroupBy)
+	if err != nil {
+		return err
+	}
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Title":     opts.title(),
+		"Language":  opts.language(quotes),
+		"Style":     template.CSS(htmlStyle),
+		"Groups":    groups,
+		"Providers": strings.Join(Providers(quotes), ", "),
+	})
+}
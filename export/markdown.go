@@ -0,0 +1,82 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+// markdownListNumber matches a line Markdown would start an ordered list with
+var markdownListNumber = regexp.MustCompile(`^(\d+)([.)])`)
+
+// Markdown writes quotes as a Markdown document with a table of contents
+func Markdown(w io.Writer, quotes []*source.Quote, opts Options) error {
+	groups, err := GroupQuotes(quotes, opts.GroupBy)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n\n", markdownEscape(opts.title()))
+	if len(groups) > 1 {
+		fmt.Fprintln(bw, "## Contents")
+		fmt.Fprintln(bw)
+		for _, g := range groups {
+			fmt.Fprintf(bw, "-   [%s](#%s) (%d)\n", markdownEscape(g.Name), g.Anchor, len(g.Quotes))
+		}
+		fmt.Fprintln(bw)
+	}
+
+	for _, g := range groups {
+		if len(groups) > 1 {
+			fmt.Fprintf(bw, "<a id=\"%s\"></a>\n\n## %s\n\n", g.Anchor, markdownEscape(g.Name))
+		}
+		for _, q := range g.Quotes {
+			for _, line := range strings.Split(strings.TrimSpace(q.Text), "\n") {
+				fmt.Fprintf(bw, "> %s\n", markdownEscape(line))
+			}
+			if attribution := q.Attribution(); attribution != "" {
+				fmt.Fprintln(bw, ">")
+				if q.URL != "" {
+					fmt.Fprintf(bw, "> — [%s](%s)\n", markdownEscape(attribution), markdownURL(q.URL))
+				} else {
+					fmt.Fprintf(bw, "> — %s\n", markdownEscape(attribution))
+				}
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+
+	if providers := Providers(quotes); len(providers) > 0 {
+		fmt.Fprintf(bw, "---\n\n_Quotes provided by %s._\n", strings.Join(providers, ", "))
+	}
+	return bw.Flush()
+}
+
+// markdownEscape backslash-escapes the characters Markdown would read as
+// markup in a heading, quote or link text, e.g. an author named "*NSYNC",
+// and the list and heading markers a line may start with
+func markdownEscape(s string) string {
+	var b strings.Builder
+	for _, r := range strings.Join(strings.Fields(s), " ") {
+		if strings.ContainsRune("\\`*_[]<>#|!~", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	escaped := b.String()
+	if escaped != "" && strings.ContainsRune("-+=", rune(escaped[0])) {
+		return "\\" + escaped
+	}
+	return markdownListNumber.ReplaceAllString(escaped, "$1\\$2")
+}
+
+// markdownURL writes a link destination in angle brackets, so spaces and
+// parentheses do not end it; angle brackets in it are percent-encoded
+func markdownURL(url string) string {
+	return "<" + strings.NewReplacer("<", "%3C", ">", "%3E", "\n", "").Replace(url) + ">"
+}
@@ -5,4 +5,5 @@ go 1.16
 require (
 	github.com/manifoldco/promptui v0.8.0
 	github.com/pterm/pterm v0.12.29
+	golang.org/x/image v0.0.0-20210628002857-a66eb6448b8d
 )
@@ -0,0 +1,230 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const ANNOTATIONS_FILE = "annotations.json"
+
+var (
+	annotationsOnce sync.Once
+	annotations     *source.Annotations
+	annotationsErr  error
+)
+
+// loadAnnotations opens the user's annotations once per run
+func loadAnnotations() (*source.Annotations, error) {
+	annotationsOnce.Do(func() {
+		path, err := dataPath(ANNOTATIONS_FILE)
+		if err != nil {
+			annotationsErr = err
+			return
+		}
+		annotations, annotationsErr = source.OpenAnnotations(path)
+	})
+	return annotations, annotationsErr
+}
+
+// annotateQuote asks for the rating, note and tags of q and stores them
+func (t *Term) annotateQuote(q *source.Quote) {
+	notes, err := loadAnnotations()
+	if err != nil {
+		pterm.Error.Println(err)
+		return
+	}
+	an := notes.Get(q.ID)
+	if an == nil {
+		an = &source.Annotation{}
+	}
+
+	rating, err := promptNumber(fmt.Sprintf("Rating (%d-%d, 0 for none, now %d)", source.MIN_RATING, source.MAX_RATING, an.Rating),
+		0, source.MAX_RATING)
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+	notePrompt := promptui.Prompt{
+		Label:     "Note",
+		Default:   an.Note,
+		AllowEdit: true,
+	}
+	note, err := notePrompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+	tagsPrompt := promptui.Prompt{
+		Label:     "Tags (comma separated)",
+		Default:   strings.Join(an.Tags, ", "),
+		AllowEdit: true,
+	}
+	tags, err := tagsPrompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+
+	an.Rating, an.Note, an.Tags = rating, note, nil
+	for _, tag := range strings.Split(tags, ",") {
+		an.Tags = append(an.Tags, strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	}
+	if err := notes.Set(q, an); err != nil {
+		pterm.Error.Printfln("could not save annotation: %v", err)
+		return
+	}
+	q.Print()
+}
+
+// selectQuoteToAnnotate picks one of the quotes on screen and annotates it
+func (t *Term) selectQuoteToAnnotate(quotes []*source.Quote) {
+	if i, ok := t.selectQuoteOnScreen("Annotate which quote?", quotes); ok {
+		t.annotateQuote(quotes[i])
+	}
+}
+
+// selectQuoteOnScreen asks for one of the quotes, by the start of its text
+func (t *Term) selectQuoteOnScreen(label string, quotes []*source.Quote) (int, bool) {
+	items := make([]string, 0, len(quotes)+1)
+	for _, q := range quotes {
+		text := strings.Join(strings.Fields(q.Text), " ")
+		if len([]rune(text)) > 60 {
+			text = string([]rune(text)[:57]) + "..."
+		}
+		items = append(items, text)
+	}
+	items = append(items, GO_BACK)
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+		Size:  10,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return 0, false
+	}
+	return i, i < len(quotes)
+}
+
+// selectAnnotationFilter adds a filter like `rating>=4 tag:onboarding` to qo
+func (t *Term) selectAnnotationFilter(qo *source.QueryOptions) error {
+	hint := "e.g. rating>=4 tag:onboarding note:team"
+	if notes, err := loadAnnotations(); err == nil {
+		if tags := notes.Tags(); len(tags) > 0 {
+			hint = "tags: " + strings.Join(tags, ", ")
+		}
+	}
+	pterm.Info.Println(hint)
+	prompt := promptui.Prompt{
+		Label: "Filter:",
+		Validate: func(input string) error {
+			return source.ParseFilter(input, &source.QueryOptions{})
+		},
+	}
+	expr, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	return source.ParseFilter(expr, qo)
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "annotate",
+		usage: "rate, note and tag a quote by id (see `goqu list -plain -ids`), without changes print its annotation",
+		run:   runAnnotate,
+	})
+}
+
+func runAnnotate(ctx context.Context, args []string) error {
+	fs := newFlagSet("annotate")
+	id := fs.String("id", "", "id of the quote")
+	rating := fs.Int("rating", -1, fmt.Sprintf("rating from %d to %d, 0 to remove it", source.MIN_RATING, source.MAX_RATING))
+	note := fs.String("note", "", "note replacing the current one, - to remove it")
+	var tags, untags stringList
+	fs.Var(&tags, "tag", "add a personal tag, repeatable")
+	fs.Var(&untags, "untag", "remove a personal tag, repeatable")
+	all := fs.Bool("all", false, "print every annotated quote")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	notes, err := loadAnnotations()
+	if err != nil {
+		return err
+	}
+
+	if *all {
+		for _, id := range notes.IDs() {
+			printAnnotated(id, notes.Get(id))
+		}
+		return nil
+	}
+	if *id == "" {
+		return fmt.Errorf("-id is required")
+	}
+	an := notes.Get(*id)
+	if an == nil {
+		an = &source.Annotation{}
+	}
+	changed := false
+	if *rating >= 0 {
+		an.Rating, changed = *rating, true
+	}
+	if *note != "" {
+		an.Note, changed = *note, true
+		if *note == "-" {
+			an.Note = ""
+		}
+	}
+	if len(tags) > 0 {
+		an.Tags, changed = append(an.Tags, tags...), true
+	}
+	if len(untags) > 0 {
+		var kept []string
+		for _, tag := range an.Tags {
+			drop := false
+			for _, u := range untags {
+				drop = drop || strings.EqualFold(tag, u)
+			}
+			if !drop {
+				kept = append(kept, tag)
+			}
+		}
+		an.Tags, changed = kept, true
+	}
+
+	if changed {
+		if err := notes.Set(&source.Quote{ID: *id}, an); err != nil {
+			return err
+		}
+		if an = notes.Get(*id); an == nil {
+			fmt.Printf("Annotation of %s removed\n", *id)
+			return nil
+		}
+	}
+	if an == nil {
+		return fmt.Errorf("quote %s has no annotation", *id)
+	}
+	printAnnotated(*id, an)
+	return nil
+}
+
+func printAnnotated(id string, an *source.Annotation) {
+	line := id
+	if an.Text != "" {
+		line += "\t" + strings.Join(strings.Fields(an.Text), " ")
+		if an.Author != "" {
+			line += " — " + an.Author
+		}
+	}
+	fmt.Println(line)
+	fmt.Println(an.Sprint())
+}
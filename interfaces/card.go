@@ -0,0 +1,100 @@
+package interfaces
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/card"
+	"github.com/custompointofview/goqu/source"
+)
+
+var cardRenderers = map[string]func(w io.Writer, q *source.Quote, opts card.Options) error{
+	"png": card.PNG,
+	"svg": card.SVG,
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "card",
+		usage: "render a quote as a PNG or SVG image card",
+		run:   runCard,
+	})
+}
+
+func runCard(ctx context.Context, args []string) error {
+	fs := newFlagSet("card")
+	srcName := fs.String("source", "quotegarden", SOURCE_USAGE)
+	output := fs.String("o", "quote.png", "output file, the extension picks png or svg")
+	theme := fs.String("theme", card.DEFAULT_THEME, "theme: "+strings.Join(card.ThemeNames(), ", "))
+	background := fs.String("bg", "", "background colour as #rrggbb, overrides the theme")
+	width := fs.Int("width", card.DEFAULT_WIDTH, "card width in pixels")
+	height := fs.Int("height", card.DEFAULT_HEIGHT, "card height in pixels")
+	noAuthor := fs.Bool("no-author", false, "leave out the author attribution")
+	text := fs.String("text", "", "render this text instead of a quote from the source")
+	author := fs.String("author", "", "pick a quote by this author, or attribute -text to it")
+	genre := fs.String("genre", "", "pick a quote of this genre")
+	query := fs.String("query", "", "pick a quote containing this term")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+	render, ok := cardRenderers[format]
+	if !ok {
+		return fmt.Errorf("unknown card format %q, use .png or .svg", format)
+	}
+	opts := card.Options{Width: *width, Height: *height, HideAuthor: *noAuthor}
+	if opts.Theme, ok = card.Themes[*theme]; !ok {
+		return fmt.Errorf("unknown theme: %s", *theme)
+	}
+	if *background != "" {
+		bg, err := card.ParseColor(*background)
+		if err != nil {
+			return err
+		}
+		opts.Background = bg
+	}
+
+	q := &source.Quote{Text: *text, Author: *author}
+	if *text == "" {
+		var err error
+		if q, err = pickQuote(ctx, *srcName, &source.QueryOptions{Author: *author, Genre: *genre, Query: *query}); err != nil {
+			return err
+		}
+	}
+
+	// a failed render must not leave a truncated image behind
+	var buf bytes.Buffer
+	if err := render(&buf, q, opts); err != nil {
+		return err
+	}
+	tmp := *output + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, *output); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	pterm.Success.Printfln("Card written to %s", *output)
+	return nil
+}
+
+// pickQuote returns a random quote of the source matching the options
+func pickQuote(ctx context.Context, srcName string, qo *source.QueryOptions) (*source.Quote, error) {
+	src, err := openSource(srcName)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return newQuotePicker(src, qo).next(ctx)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"regexp"
@@ -20,6 +21,7 @@ import (
 )
 
 const GO_BACK = "< Go back"
+const LIBRARY_DB = "library.db"
 
 var DEFAULT_SOURCE = source.NewQuoteGarden()
 var DEFAULT_SOURCE_LIMIT = 9
@@ -29,15 +31,26 @@ type Term struct {
 	Error       chan error
 	Done        chan bool
 	source      source.Sources
+	sourceName  string
 	sourceLimit int
 }
 
 // NewTerm creates a Term object
 func NewTerm() *Term {
+	var src source.Sources = DEFAULT_SOURCE
+	err := configureQuoteGarden(DEFAULT_SOURCE)
+	if err == nil {
+		src, err = withFailover("quotegarden", DEFAULT_SOURCE)
+	}
+	if err != nil {
+		pterm.Warning.Println(err)
+		src = DEFAULT_SOURCE
+	}
 	return &Term{
 		Error:       make(chan error),
 		Done:        make(chan bool),
-		source:      DEFAULT_SOURCE,
+		source:      src,
+		sourceName:  "quotegarden",
 		sourceLimit: DEFAULT_SOURCE_LIMIT,
 	}
 }
@@ -69,7 +82,7 @@ func (t *Term) selectCommand(ctx context.Context) {
 	pterm.DefaultSection.Println("Main menu")
 
 	var cmdOptions = []string{"Configure", "Get Random Quote", "Get Based On Genres",
-		"Get Based On Authors", "Search...", "Exit"}
+		"Get Based On Authors", "Search...", "Recent searches", "Exit"}
 	prompt := promptui.Select{
 		Label: "What would you like?",
 		Items: cmdOptions,
@@ -111,6 +124,12 @@ func (t *Term) selectCommand(ctx context.Context) {
 			pterm.DefaultSection.Println("Searching Quotes...")
 			t.selectSearch(ctx, nil)
 		}()
+	case cmdOptions[5]:
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.recentSearches(ctx)
+		}()
 	case "Exit":
 		t.Done <- true
 	}
@@ -139,7 +158,9 @@ func (t *Term) configure() {
 }
 
 func (t *Term) configureSelectSource() {
-	var cmdOptions = []string{"QuoteGarden", GO_BACK}
+	var cmdOptions = []string{"QuoteGarden", "Local mirror", "My library", "Fortune files...", "Collection..."}
+	externals := externalNames()
+	cmdOptions = append(append(cmdOptions, externals...), GO_BACK)
 	prompt := promptui.Select{
 		Label: "Source for quotes",
 		Items: cmdOptions,
@@ -151,12 +172,51 @@ func (t *Term) configureSelectSource() {
 	}
 	switch result {
 	case cmdOptions[0]:
-		t.source = source.NewQuoteGarden()
+		t.useSource("quotegarden")
+	case cmdOptions[1]:
+		t.useSource("mirror")
+	case cmdOptions[2]:
+		t.useSource("library")
+	case cmdOptions[3]:
+		pathPrompt := promptui.Prompt{
+			Label: "Fortune file or directory",
+		}
+		path, err := pathPrompt.Run()
+		if err != nil {
+			t.Error <- fmt.Errorf("prompt failed: %v", err)
+			return
+		}
+		t.useSource("fortune:" + path)
+	case cmdOptions[4]:
+		if name, ok := t.selectCollection("Collection", false); ok {
+			t.useSource("collection:" + name)
+		}
 	case GO_BACK:
 		return
+	default:
+		t.useSource(result)
 	}
 }
 
+// useSource opens the named source, see openSource, and reports whether it could
+func (t *Term) useSource(name string) bool {
+	src, err := openSource(name)
+	if err != nil {
+		pterm.Error.Printfln("could not open source: %v", err)
+		return false
+	}
+	t.setSource(src)
+	t.sourceName = name
+	return true
+}
+
+func (t *Term) setSource(src source.Sources) {
+	if closer, ok := t.source.(io.Closer); ok {
+		closer.Close()
+	}
+	t.source = src
+}
+
 func (t *Term) configureSelectSourceLimit() {
 	validate := func(input string) error {
 		_, err := strconv.ParseInt(input, 10, 32)
@@ -180,7 +240,7 @@ func (t *Term) configureSelectSourceLimit() {
 }
 
 func (t *Term) randomQuote(ctx context.Context) {
-	quote, err := t.source.RandomQuote(ctx)
+	quote, err := queryable(t.source).RandomQuote(ctx)
 	if err != nil {
 		t.Error <- fmt.Errorf("could not get random quote from source: %v", err)
 		return
@@ -189,31 +249,10 @@ func (t *Term) randomQuote(ctx context.Context) {
 }
 
 func (t *Term) selectGenre(ctx context.Context) {
-	// make HTTP request
-	items, err := t.source.AllGenres(ctx)
-	if err != nil {
-		t.Error <- fmt.Errorf("could not get categories from source: %v", err)
+	qo := &source.QueryOptions{}
+	if !t.chooseGenres(ctx, qo) || !qo.Selects() {
 		return
 	}
-	pterm.Info.Printfln("Number of items: %+v", len(items))
-
-	// filter or not the items
-	items = t.selectFilter(items)
-
-	// select from the response genres
-	prompt := promptui.Select{
-		Label: "Select genre",
-		Items: items,
-	}
-	_, selection, err := prompt.Run()
-	if err != nil {
-		t.Error <- fmt.Errorf("prompt failed: %v", err)
-		return
-	}
-	// create query options & go further
-	qo := &source.QueryOptions{
-		Genre: selection,
-	}
 	t.goFurther(ctx, qo)
 }
 
@@ -283,32 +322,10 @@ func (t *Term) filter(items []string, filter string) []string {
 }
 
 func (t *Term) selectAuthor(ctx context.Context) {
-	// make HTTP request
-	items, err := t.source.AllAuthors(ctx)
-	if err != nil {
-		t.Error <- fmt.Errorf("could not get categories from source: %v", err)
+	qo := &source.QueryOptions{}
+	if !t.chooseAuthors(ctx, qo) || !qo.Selects() {
 		return
 	}
-	pterm.Info.Printfln("Number of items: %+v", len(items))
-
-	// filter or not the items
-	items = t.selectFilter(items)
-
-	// select from the response genres
-	prompt := promptui.Select{
-		Label: "Select author",
-		Items: items,
-	}
-
-	_, selection, err := prompt.Run()
-	if err != nil {
-		t.Error <- fmt.Errorf("prompt failed %v", err)
-		return
-	}
-	// create query options & go further
-	qo := &source.QueryOptions{
-		Author: selection,
-	}
 	t.goFurther(ctx, qo)
 }
 
@@ -344,20 +361,19 @@ func (t *Term) selectSearch(ctx context.Context, qo *source.QueryOptions) {
 	}
 
 	// create query options & go further
-	qoTemp := &source.QueryOptions{
-		Query: selection,
-	}
+	qoTemp := &source.QueryOptions{}
 	if qo != nil {
-		qoTemp.Author = qo.Author
-		qoTemp.Genre = qo.Genre
+		*qoTemp = *qo
+		qoTemp.Page, qoTemp.Limit = 0, 0
 	}
+	qoTemp.Query = selection
 	t.goFurther(ctx, qoTemp)
 }
 
 func (t *Term) goFurther(ctx context.Context, qo *source.QueryOptions) {
 	for {
 		t.printSection(qo)
-		itemSelection := []string{"Show all quotes", "Get random quote", "Add a filter...", GO_BACK}
+		itemSelection := []string{"Show all quotes", "Get random quote", "Add a filter...", "Genres and authors...", "Sort by...", "Save this search", GO_BACK}
 		prompt := promptui.Select{
 			Label: "What would you like?",
 			Items: itemSelection,
@@ -375,37 +391,69 @@ func (t *Term) goFurther(ctx context.Context, qo *source.QueryOptions) {
 		case itemSelection[1]:
 			t.showRandomQuote(ctx, qo)
 		case itemSelection[2]:
-			t.selectSearch(ctx, qo)
+			t.selectConstraint(ctx, qo)
+		case itemSelection[3]:
+			t.selectMany(ctx, qo)
+		case itemSelection[4]:
+			t.selectSort(qo)
+		case itemSelection[5]:
+			t.saveSearch(qo)
 		case GO_BACK:
 			return
 		}
 	}
 }
 
+func (t *Term) selectSort(qo *source.QueryOptions) {
+	const sourceOrder = "source order"
+	items := append([]string{sourceOrder}, source.SortKeys...)
+	prompt := promptui.Select{
+		Label: "Sort quotes by",
+		Items: items,
+	}
+	_, result, err := prompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+	qo.Sort, qo.Seed, qo.SeedPicked = result, 0, false
+	switch result {
+	case sourceOrder:
+		qo.Sort = ""
+	case source.SORT_RANDOM:
+		reseed(qo)
+	}
+}
+
 func (t *Term) showAllQuotes(ctx context.Context, qo *source.QueryOptions) {
-	pageSelection := 1
+	recordSearch(t.sourceName, qo)
+	pg := &pager{page: 1, limit: t.sourceLimit}
+	query := *qo
+	query.Page, query.Limit = 0, 0
+	pages := newPrefetcher(ctx, queryable(t.source), &query)
+	defer pages.stop()
 
 	for {
 		// query based on selection
-		opt := &source.QueryOptions{
-			Limit:  int32(t.sourceLimit),
-			Page:   int32(pageSelection),
-			Genre:  qo.Genre,
-			Author: qo.Author,
-			Query:  qo.Query,
-		}
-		quotes, pag, err := t.source.Quotes(ctx, opt)
+		quotes, pag, err := pages.get(int32(pg.page), int32(pg.limit))
 		if err != nil {
 			t.Error <- fmt.Errorf("quotes query failed: %v", err)
 			return
 		}
-		title := fmt.Sprintf("PAGE %d/%d", pageSelection, pag.TotalPages)
-		t.source.PrintQuotesPage(title, quotes, int(math.Sqrt(float64(t.sourceLimit))))
+		pg.totalPages = pag.TotalPages
+		if pg.page > pg.lastPage() {
+			// the results shrank since the last page was loaded
+			pg.last()
+			continue
+		}
+		pages.around(int32(pg.page), int32(pg.limit), pag.TotalPages)
+		t.source.PrintQuotesPage(pg.title(pag), quotes, int(math.Sqrt(float64(pg.limit))))
 
-		itemSelection := []string{"Next Page", "Previous Page", GO_BACK}
+		itemSelection := []string{"Next Page", "Previous Page", "First Page", "Last Page", "Go to page...", "Page size...", "Annotate a quote...", "Add to collection...", GO_BACK}
 		prompt := promptui.Select{
 			Label: "Select action",
 			Items: itemSelection,
+			Size:  len(itemSelection),
 		}
 
 		_, result, err := prompt.Run()
@@ -415,16 +463,31 @@ func (t *Term) showAllQuotes(ctx context.Context, qo *source.QueryOptions) {
 		}
 		switch result {
 		case itemSelection[0]:
-			pageSelection += 1
-			if pageSelection >= pag.TotalPages {
-				pageSelection = 1
-			}
+			pg.next()
 		case itemSelection[1]:
-			if pageSelection == 1 {
-				pageSelection = pag.TotalPages
-			} else {
-				pageSelection -= 1
+			pg.prev()
+		case itemSelection[2]:
+			pg.first()
+		case itemSelection[3]:
+			pg.last()
+		case itemSelection[4]:
+			page, err := promptNumber(fmt.Sprintf("Page (1-%d)", pg.lastPage()), 1, pg.lastPage())
+			if err != nil {
+				t.Error <- fmt.Errorf("prompt failed: %v", err)
+				return
+			}
+			pg.jump(page)
+		case itemSelection[5]:
+			limit, err := promptNumber(fmt.Sprintf("Quotes per page (now %d)", pg.limit), 1, 100)
+			if err != nil {
+				t.Error <- fmt.Errorf("prompt failed: %v", err)
+				return
 			}
+			pg.resize(limit)
+		case itemSelection[6]:
+			t.selectQuoteToAnnotate(quotes)
+		case itemSelection[7]:
+			t.selectQuoteToCollect(quotes)
 		case GO_BACK:
 			return
 		}
@@ -432,30 +495,41 @@ func (t *Term) showAllQuotes(ctx context.Context, qo *source.QueryOptions) {
 }
 
 func (t *Term) showRandomQuote(ctx context.Context, qo *source.QueryOptions) {
+	recordSearch(t.sourceName, qo)
 	pageSelection := 1
+	src := queryable(t.source)
 	for {
 		// query based on selection
-		opt := &source.QueryOptions{
-			Limit:  int32(t.sourceLimit),
-			Page:   int32(pageSelection),
-			Genre:  qo.Genre,
-			Author: qo.Author,
-			Query:  qo.Query,
-		}
-		quotes, pag, err := t.source.Quotes(ctx, opt)
+		opt := *qo
+		opt.Limit, opt.Page = int32(t.sourceLimit), int32(pageSelection)
+		opt.Sort, opt.Seed = "", 0
+		quotes, pag, err := src.Quotes(ctx, &opt)
 		if err != nil {
 			t.Error <- fmt.Errorf("quotes query failed: %v", err)
 			return
 		}
+		if len(quotes) == 0 && pageSelection != 1 {
+			// a page announced while the source was still being read may
+			// turn out empty, the first page has quotes if any match
+			pageSelection = 1
+			continue
+		}
+		if len(quotes) == 0 {
+			pterm.Warning.Println("No quote matches the selection")
+			return
+		}
 		// get random quote
 		rand.Seed(time.Now().Unix())
 		randQ := quotes[rand.Intn(len(quotes))]
 		randQ.Print()
 		// change page
-		pageSelection = rand.Intn(pag.TotalPages) + 1
+		pageSelection = 1
+		if pag != nil && pag.TotalPages > 1 {
+			pageSelection = rand.Intn(pag.TotalPages) + 1
+		}
 
 		// after menu
-		itemSelection := []string{"Get Another", GO_BACK}
+		itemSelection := []string{"Get Another", "Rate and annotate", "Add to collection", GO_BACK}
 		prompt := promptui.Select{
 			Label: "Random quote",
 			Items: itemSelection,
@@ -469,6 +543,10 @@ func (t *Term) showRandomQuote(ctx context.Context, qo *source.QueryOptions) {
 		switch result {
 		case itemSelection[0]:
 			// just continue with another request
+		case itemSelection[1]:
+			t.annotateQuote(randQ)
+		case itemSelection[2]:
+			t.collectQuote(randQ)
 		case GO_BACK:
 			return
 		}
@@ -508,12 +586,5 @@ func (t *Term) printExit() {
 }
 
 func (t *Term) printSection(qo *source.QueryOptions) {
-	var selection []string
-	options := []string{qo.Genre, qo.Author, qo.Query}
-	for _, o := range options {
-		if strings.TrimSpace(o) != "" {
-			selection = append(selection, o)
-		}
-	}
-	pterm.DefaultSection.Printfln("Selected options: %s", strings.Join(selection, " & "))
+	pterm.DefaultSection.Printfln("Selected options: %s", describeQuery(qo))
 }
@@ -0,0 +1,466 @@
+package interfaces
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	COLLECTIONS_DIR = "collections"
+	// quotes picked by `goqu collection add` when -max is not given
+	DEFAULT_COLLECTION_ADD = 1
+)
+
+func openCollections() (*source.Collections, error) {
+	dir, err := dataPath(COLLECTIONS_DIR)
+	if err != nil {
+		return nil, err
+	}
+	return source.OpenCollections(dir), nil
+}
+
+func openCollection(name string) (*source.Collection, error) {
+	cs, err := openCollections()
+	if err != nil {
+		return nil, err
+	}
+	return cs.Get(name)
+}
+
+// addToCollection adds quotes to the named collection, creating it when missing
+func addToCollection(name string, quotes ...*source.Quote) (int, error) {
+	cs, err := openCollections()
+	if err != nil {
+		return 0, err
+	}
+	c := source.NewCollection(name)
+	if cs.Exists(name) {
+		if c, err = cs.Get(name); err != nil {
+			return 0, err
+		}
+	}
+	added := c.Add(quotes...)
+	return added, cs.Save(c)
+}
+
+// selectCollection asks for a collection, allowNew lets a new name be typed
+func (t *Term) selectCollection(label string, allowNew bool) (string, bool) {
+	cs, err := openCollections()
+	if err != nil {
+		pterm.Error.Println(err)
+		return "", false
+	}
+	list, err := cs.List()
+	if err != nil {
+		pterm.Error.Println(err)
+		return "", false
+	}
+	const newCollection = "New collection..."
+	var items []string
+	for _, c := range list {
+		items = append(items, c.Name)
+	}
+	if allowNew {
+		items = append(items, newCollection)
+	}
+	if len(items) == 0 {
+		pterm.Info.Println("No collections yet, add quotes to one while browsing")
+		return "", false
+	}
+	items = append(items, GO_BACK)
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+		Size:  10,
+	}
+	_, result, err := prompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return "", false
+	}
+	switch result {
+	case GO_BACK:
+		return "", false
+	case newCollection:
+		namePrompt := promptui.Prompt{
+			Label: "Name:",
+			Validate: func(input string) error {
+				if source.CollectionFile(input) == source.COLLECTION_EXT {
+					return fmt.Errorf("name must contain letters or digits")
+				}
+				return nil
+			},
+		}
+		name, err := namePrompt.Run()
+		if err != nil {
+			t.Error <- fmt.Errorf("prompt failed: %v", err)
+			return "", false
+		}
+		return strings.TrimSpace(name), true
+	}
+	return result, true
+}
+
+// collectQuote adds q to a collection picked by the user
+func (t *Term) collectQuote(q *source.Quote) {
+	name, ok := t.selectCollection("Add to collection", true)
+	if !ok {
+		return
+	}
+	added, err := addToCollection(name, q)
+	switch {
+	case err != nil:
+		pterm.Error.Printfln("could not add to %s: %v", name, err)
+	case added == 0:
+		pterm.Info.Printfln("Already in %s", name)
+	default:
+		pterm.Success.Printfln("Added to %s", name)
+	}
+}
+
+// selectQuoteToCollect picks one of the quotes on screen and adds it to a collection
+func (t *Term) selectQuoteToCollect(quotes []*source.Quote) {
+	if i, ok := t.selectQuoteOnScreen("Add which quote?", quotes); ok {
+		t.collectQuote(quotes[i])
+	}
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "collection",
+		usage: "curate named collections: list, create, show, add, remove, move, delete, export, import",
+		run:   runCollection,
+	})
+}
+
+const collectionUsage = `Usage: goqu collection list
+       goqu collection create [-description text] <name>
+       goqu collection show <name>
+       goqu collection add [-source name] [-max n] [-id id]... [selection flags] <name>
+       goqu collection remove <name> <position>...
+       goqu collection move <name> <from> <to>
+       goqu collection delete <name>
+       goqu collection export -o file.goqu [-sign] [name]...
+       goqu collection import [-replace] [-require-trusted] [-allow-untrusted] <file.goqu>
+Browse a collection with -source collection:<name>.`
+
+func runCollection(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Println(collectionUsage)
+		return fmt.Errorf("missing subcommand")
+	}
+	sub := args[0]
+	fs := newFlagSet("collection " + sub)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), collectionUsage)
+		fs.PrintDefaults()
+	}
+	var (
+		description *string
+		srcName     *string
+		max         *int
+		ids         stringList
+		selection   *queryFlags
+		output      *string
+		sign        *bool
+		keyFile     *string
+		replace     *bool
+		trusted     *bool
+		untrusted   *bool
+	)
+	switch sub {
+	case "create":
+		description = fs.String("description", "", "what the collection is for")
+	case "add":
+		srcName = fs.String("source", "quotegarden", SOURCE_USAGE)
+		max = fs.Int("max", DEFAULT_COLLECTION_ADD, "number of matching quotes to add, 0 for all")
+		fs.Var(&ids, "id", "add the quote of this id among the matching ones, repeatable")
+		selection = addQueryFlags(fs)
+	case "export":
+		output = fs.String("o", "", "bundle file to write, e.g. onboarding"+source.BUNDLE_EXT)
+		sign = fs.Bool("sign", false, "sign the bundle, see goqu key generate")
+		keyFile = fs.String("key", defaultSigningKey(), "private key signing the bundle")
+	case "import":
+		replace = fs.Bool("replace", false, "replace collections of the same name instead of adding to them")
+		trusted = fs.Bool("require-trusted", false, "refuse bundles not signed by a trusted key, also settable in "+CONFIG_FILE)
+		untrusted = fs.Bool("allow-untrusted", false, "accept a bundle unsigned or signed by an unknown key although keys are trusted")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cs, err := openCollections()
+	if err != nil {
+		return err
+	}
+	name := ""
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+	needArgs := func(n int) error {
+		if fs.NArg() != n {
+			fs.Usage()
+			return fmt.Errorf("wrong number of arguments")
+		}
+		return nil
+	}
+
+	switch sub {
+	case "list":
+		list, err := cs.List()
+		if err != nil {
+			return err
+		}
+		for _, c := range list {
+			fmt.Printf("%s\t%d quotes\t%s\n", c.Name, len(c.Items), c.Description)
+		}
+	case "create":
+		if err := needArgs(1); err != nil {
+			return err
+		}
+		if cs.Exists(name) {
+			return fmt.Errorf("collection %q exists", name)
+		}
+		c := source.NewCollection(name)
+		c.Description = *description
+		if err := cs.Save(c); err != nil {
+			return err
+		}
+		pterm.Success.Printfln("Collection %s created, add quotes with: goqu collection add %q", name, name)
+	case "show":
+		if err := needArgs(1); err != nil {
+			return err
+		}
+		c, err := cs.Get(name)
+		if err != nil {
+			return err
+		}
+		for i, q := range c.Items {
+			fmt.Printf("%d\t%s\n", i+1, q.PostText())
+		}
+	case "add":
+		if err := needArgs(1); err != nil {
+			return err
+		}
+		return collectionAdd(ctx, name, *srcName, selection, *max, ids)
+	case "remove":
+		if fs.NArg() < 2 {
+			fs.Usage()
+			return fmt.Errorf("expected a name and positions")
+		}
+		c, err := cs.Get(name)
+		if err != nil {
+			return err
+		}
+		positions, err := parsePositions(fs.Args()[1:])
+		if err != nil {
+			return err
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(positions)))
+		// from the last one, so the positions stay valid
+		for i, p := range positions {
+			if i > 0 && p == positions[i-1] {
+				continue
+			}
+			if err := c.Remove(p - 1); err != nil {
+				return err
+			}
+		}
+		return cs.Save(c)
+	case "move":
+		if err := needArgs(3); err != nil {
+			return err
+		}
+		c, err := cs.Get(name)
+		if err != nil {
+			return err
+		}
+		positions, err := parsePositions(fs.Args()[1:])
+		if err != nil {
+			return err
+		}
+		if err := c.Move(positions[0]-1, positions[1]-1); err != nil {
+			return err
+		}
+		return cs.Save(c)
+	case "delete":
+		if err := needArgs(1); err != nil {
+			return err
+		}
+		return cs.Delete(name)
+	case "export":
+		keyPath := ""
+		if *sign {
+			keyPath = *keyFile
+		}
+		return collectionExport(cs, *output, keyPath, fs.Args())
+	case "import":
+		if err := needArgs(1); err != nil {
+			return err
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		return collectionImport(cs, name, *replace, *trusted || cfg.Bundles.RequireTrusted, *untrusted)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown subcommand: %s", sub)
+	}
+	return nil
+}
+
+// parsePositions reads positions counted from 1
+func parsePositions(args []string) ([]int, error) {
+	positions := make([]int, 0, len(args))
+	for _, a := range args {
+		n, err := strconv.Atoi(a)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid position %q", a)
+		}
+		positions = append(positions, n)
+	}
+	return positions, nil
+}
+
+// collectionAdd adds the quotes of a source matching the selection
+func collectionAdd(ctx context.Context, name, srcName string, selection *queryFlags, max int, ids stringList) error {
+	qo, err := selection.options()
+	if err != nil {
+		return err
+	}
+	src, err := openSource(srcName)
+	if err != nil {
+		return err
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	opt := *qo
+	opt.Limit = 100
+	limit := max
+	if len(ids) > 0 {
+		// the ids are looked for among all the matching quotes
+		limit = source.SORT_MAX_QUOTES
+	}
+	quotes, truncated, err := source.CollectQuotes(ctx, queryable(src), &opt, limit)
+	if err != nil {
+		return fmt.Errorf("could not query source: %v", err)
+	}
+	if truncated {
+		warnTruncated(false)
+	}
+	if len(ids) > 0 {
+		var picked []*source.Quote
+		for _, id := range ids {
+			found := false
+			for _, q := range quotes {
+				if q.ID == id {
+					picked, found = append(picked, q), true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("no quote %s among the %d matching quotes", id, len(quotes))
+			}
+		}
+		quotes = picked
+	}
+	if len(quotes) == 0 {
+		return fmt.Errorf("no quote matches the selection")
+	}
+
+	added, err := addToCollection(name, quotes...)
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("%d quotes added to %s, %d already in it", added, name, len(quotes)-added)
+	return nil
+}
+
+// collectionExport writes the named collections, or all, to a bundle
+// signed with the key at keyPath unless it is empty
+func collectionExport(cs *source.Collections, output, keyPath string, names []string) error {
+	if output == "" {
+		return fmt.Errorf("-o is required")
+	}
+	var key ed25519.PrivateKey
+	if keyPath != "" {
+		var err error
+		if key, err = source.LoadPrivateKey(keyPath); err != nil {
+			return fmt.Errorf("could not load signing key, create one with goqu key generate: %v", err)
+		}
+	}
+	var collections []*source.Collection
+	if len(names) == 0 {
+		list, err := cs.List()
+		if err != nil {
+			return err
+		}
+		collections = list
+	}
+	for _, name := range names {
+		c, err := cs.Get(name)
+		if err != nil {
+			return err
+		}
+		collections = append(collections, c)
+	}
+	if len(collections) == 0 {
+		return fmt.Errorf("no collection to export")
+	}
+	if err := source.WriteBundleFile(output, collections, key); err != nil {
+		return err
+	}
+	if key != nil {
+		pterm.Success.Printfln("%d collections written to %s, signed by key %s", len(collections), output,
+			source.KeyID(key.Public().(ed25519.PublicKey)))
+		return nil
+	}
+	pterm.Success.Printfln("%d collections written to %s", len(collections), output)
+	return nil
+}
+
+// collectionImport stores the collections of a bundle, adding to the
+// existing ones of the same name unless replace is set. Tampered bundles
+// are always refused, unsigned or untrusted ones when requireTrusted is set
+// or once a key is trusted, unless allowUntrusted is set.
+func collectionImport(cs *source.Collections, path string, replace, requireTrusted, allowUntrusted bool) error {
+	manifest, collections, err := source.ReadBundleFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %v", path, err)
+	}
+	pterm.Info.Printfln("Bundle version %d of %s", manifest.Version, manifest.CreatedAt.Local().Format("2006-01-02 15:04"))
+	if err := checkBundleSigner(manifest, requireTrusted, allowUntrusted); err != nil {
+		return fmt.Errorf("refusing %s: %v", path, err)
+	}
+	for _, c := range collections {
+		if !replace && cs.Exists(c.Name) {
+			current, err := cs.Get(c.Name)
+			if err != nil {
+				return err
+			}
+			added := current.Add(c.Items...)
+			if err := cs.Save(current); err != nil {
+				return err
+			}
+			pterm.Success.Printfln("%s: %d quotes added", c.Name, added)
+			continue
+		}
+		if err := cs.Save(c); err != nil {
+			return err
+		}
+		pterm.Success.Printfln("%s: %d quotes", c.Name, len(c.Items))
+	}
+	return nil
+}
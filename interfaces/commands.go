@@ -0,0 +1,48 @@
+package interfaces
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands []command
+
+// RunCommand executes a non-interactive command by name
+func RunCommand(ctx context.Context, name string, args []string) error {
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(ctx, args); err != flag.ErrHelp {
+				return err
+			}
+			return nil
+		}
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return nil
+	}
+	printUsage()
+	return fmt.Errorf("unknown command: %s", name)
+}
+
+func printUsage() {
+	var lines []string
+	for _, c := range commands {
+		lines = append(lines, fmt.Sprintf("  %-12s %s", c.name, c.usage))
+	}
+	fmt.Fprintf(os.Stderr, "Usage: goqu [global flags] [command] [flags]\n\nWithout a command goqu starts in interactive mode.\n\nCommands:\n%s\n",
+		strings.Join(lines, "\n"))
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("goqu "+name, flag.ContinueOnError)
+}
@@ -0,0 +1,258 @@
+package interfaces
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	CONFIG_FILE = "config.json"
+	BUDGET_FILE = "budget.json"
+
+	// secrets can stay out of the config file and the shell history
+	HTTP_TOKEN_ENV   = "GOQU_HTTP_TOKEN"
+	HTTP_API_KEY_ENV = "GOQU_HTTP_API_KEY"
+)
+
+// Config holds the user settings kept in the data directory
+type Config struct {
+	QuoteGarden QuoteGardenConfig `json:"quotegarden"`
+	HTTP        HTTPConfig        `json:"http"`
+	Failover    FailoverConfig    `json:"failover"`
+	Bundles     BundlesConfig     `json:"bundles"`
+}
+
+type QuoteGardenConfig struct {
+	// URL is the API or server root, e.g. of a self-hosted fork
+	URL string `json:"url,omitempty"`
+	// Version pins the schema adapter instead of negotiating it
+	Version string `json:"version,omitempty"`
+}
+
+// HTTPConfig is shared by every HTTP based source
+type HTTPConfig struct {
+	Proxy        string `json:"proxy,omitempty"`
+	CACert       string `json:"caCert,omitempty"`
+	ClientCert   string `json:"clientCert,omitempty"`
+	ClientKey    string `json:"clientKey,omitempty"`
+	UserAgent    string `json:"userAgent,omitempty"`
+	Token        string `json:"token,omitempty"`
+	APIKey       string `json:"apiKey,omitempty"`
+	APIKeyHeader string `json:"apiKeyHeader,omitempty"`
+	// Timeout is a duration such as "30s"
+	Timeout string `json:"timeout,omitempty"`
+	// RateLimit is in requests per second, -1 turns it off
+	RateLimit     float64 `json:"rateLimit,omitempty"`
+	Burst         int     `json:"burst,omitempty"`
+	MaxConcurrent int     `json:"maxConcurrent,omitempty"`
+	// DailyBudget is the number of requests allowed per host and day
+	DailyBudget int `json:"dailyBudget,omitempty"`
+}
+
+// FailoverConfig protects the remote sources with fallbacks
+type FailoverConfig struct {
+	// Fallbacks are tried in order, "none" turns the failover off
+	Fallbacks []string `json:"fallbacks,omitempty"`
+	// Failures in a row before the fallbacks take over
+	Failures int `json:"failures,omitempty"`
+	// Cooldown is how long to wait before retrying, e.g. "1m"
+	Cooldown string `json:"cooldown,omitempty"`
+}
+
+// BundlesConfig decides which shared bundles are imported
+type BundlesConfig struct {
+	// RequireTrusted refuses bundles not signed by a trusted key
+	RequireTrusted bool `json:"requireTrusted,omitempty"`
+}
+
+// flagConfig holds the global flags, they win over the config file
+var flagConfig Config
+
+// ParseFlags reads the global flags given before the command
+// and returns the remaining arguments
+func ParseFlags(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("goqu", flag.ContinueOnError)
+	fs.StringVar(&flagConfig.QuoteGarden.URL, "quotegarden-url", "", "QuoteGarden API or server root URL")
+	fs.StringVar(&flagConfig.QuoteGarden.Version, "quotegarden-version", "", "QuoteGarden API version: v2, v3 or compat, empty to negotiate")
+	fs.StringVar(&flagConfig.HTTP.Proxy, "proxy", "", "HTTP(S) or socks5 proxy URL for remote sources")
+	fs.StringVar(&flagConfig.HTTP.CACert, "ca-cert", "", "PEM bundle of additional trusted CAs")
+	fs.StringVar(&flagConfig.HTTP.ClientCert, "client-cert", "", "PEM client certificate for mutual TLS")
+	fs.StringVar(&flagConfig.HTTP.ClientKey, "client-key", "", "PEM key of the client certificate")
+	fs.StringVar(&flagConfig.HTTP.UserAgent, "user-agent", "", "User-Agent sent to remote sources")
+	fs.StringVar(&flagConfig.HTTP.Token, "token", "", "bearer token sent to remote sources, or $"+HTTP_TOKEN_ENV)
+	fs.StringVar(&flagConfig.HTTP.APIKey, "api-key", "", "API key sent to remote sources, or $"+HTTP_API_KEY_ENV)
+	fs.StringVar(&flagConfig.HTTP.APIKeyHeader, "api-key-header", "", "header carrying the API key (default "+source.DEFAULT_API_KEY_HEADER+")")
+	fs.StringVar(&flagConfig.HTTP.Timeout, "timeout", "", "timeout of every remote request, e.g. 30s")
+	fs.Float64Var(&flagConfig.HTTP.RateLimit, "rate-limit", 0, fmt.Sprintf("remote requests per second, -1 for no limit (default %v)", source.DEFAULT_RATE_LIMIT))
+	fs.IntVar(&flagConfig.HTTP.Burst, "burst", 0, fmt.Sprintf("requests allowed at once above the rate (default %d)", source.DEFAULT_RATE_BURST))
+	fs.IntVar(&flagConfig.HTTP.MaxConcurrent, "max-concurrent", 0, fmt.Sprintf("remote requests in flight, -1 for no cap (default %d)", source.DEFAULT_MAX_CONCURRENT))
+	fs.IntVar(&flagConfig.HTTP.DailyBudget, "daily-budget", 0, "remote requests allowed per host and day, 0 for no budget")
+	fs.Var((*commaList)(&flagConfig.Failover.Fallbacks), "fallbacks", "comma separated sources serving while remote ones fail, none to turn off (default "+strings.Join(DEFAULT_FALLBACKS, ",")+")")
+	fs.Usage = func() {
+		printUsage()
+		fmt.Fprintln(fs.Output(), "\nGlobal flags, also settable in "+CONFIG_FILE+":")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return fs.Args(), nil
+}
+
+func loadConfig() (*Config, error) {
+	path, err := dataPath(CONFIG_FILE)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := readJSON(path, cfg); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	cfg.override(&Config{HTTP: HTTPConfig{
+		Token:  os.Getenv(HTTP_TOKEN_ENV),
+		APIKey: os.Getenv(HTTP_API_KEY_ENV),
+	}})
+	cfg.override(&flagConfig)
+	return cfg, nil
+}
+
+// override replaces the settings o has values for
+func (c *Config) override(o *Config) {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&c.QuoteGarden.URL, o.QuoteGarden.URL)
+	set(&c.QuoteGarden.Version, o.QuoteGarden.Version)
+	set(&c.HTTP.Proxy, o.HTTP.Proxy)
+	set(&c.HTTP.CACert, o.HTTP.CACert)
+	set(&c.HTTP.ClientCert, o.HTTP.ClientCert)
+	set(&c.HTTP.ClientKey, o.HTTP.ClientKey)
+	set(&c.HTTP.UserAgent, o.HTTP.UserAgent)
+	set(&c.HTTP.Token, o.HTTP.Token)
+	set(&c.HTTP.APIKey, o.HTTP.APIKey)
+	set(&c.HTTP.APIKeyHeader, o.HTTP.APIKeyHeader)
+	set(&c.HTTP.Timeout, o.HTTP.Timeout)
+	if o.HTTP.RateLimit != 0 {
+		c.HTTP.RateLimit = o.HTTP.RateLimit
+	}
+	setInt := func(dst *int, v int) {
+		if v != 0 {
+			*dst = v
+		}
+	}
+	setInt(&c.HTTP.Burst, o.HTTP.Burst)
+	setInt(&c.HTTP.MaxConcurrent, o.HTTP.MaxConcurrent)
+	setInt(&c.HTTP.DailyBudget, o.HTTP.DailyBudget)
+	if o.Failover.Fallbacks != nil {
+		c.Failover.Fallbacks = o.Failover.Fallbacks
+	}
+	setInt(&c.Failover.Failures, o.Failover.Failures)
+	set(&c.Failover.Cooldown, o.Failover.Cooldown)
+	if o.Bundles.RequireTrusted {
+		c.Bundles.RequireTrusted = true
+	}
+}
+
+// commaList is a flag holding a comma separated list
+type commaList []string
+
+func (l *commaList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *commaList) Set(v string) error {
+	*l = nil
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// HTTPOptions converts the settings for source.NewHTTPClient
+func (c *HTTPConfig) HTTPOptions() (source.HTTPOptions, error) {
+	opts := source.HTTPOptions{
+		Proxy:        c.Proxy,
+		CACert:       c.CACert,
+		ClientCert:   c.ClientCert,
+		ClientKey:    c.ClientKey,
+		UserAgent:    c.UserAgent,
+		Token:        c.Token,
+		APIKey:       c.APIKey,
+		APIKeyHeader: c.APIKeyHeader,
+	}
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return opts, fmt.Errorf("invalid timeout: %v", err)
+		}
+		opts.Timeout = timeout
+	}
+	return opts, nil
+}
+
+// newQuoteGarden returns a QuoteGarden client set up from the config
+func newQuoteGarden() (*source.QuoteGarden, error) {
+	qg := source.NewQuoteGarden()
+	return qg, configureQuoteGarden(qg)
+}
+
+func configureQuoteGarden(qg *source.QuoteGarden) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.QuoteGarden.URL != "" {
+		qg.BaseURL = cfg.QuoteGarden.URL
+	}
+	qg.Version = cfg.QuoteGarden.Version
+
+	opts, err := cfg.HTTP.HTTPOptions()
+	if err != nil {
+		return err
+	}
+	if u, err := url.Parse(qg.BaseURL); err == nil {
+		opts.CredentialHost = u.Host
+	}
+	client, err := source.NewHTTPClient(opts)
+	if err != nil {
+		return err
+	}
+	limiter, err := sharedRateLimiter(&cfg.HTTP)
+	if err != nil {
+		return err
+	}
+	client.Transport = limiter.Transport(client.Transport)
+	qg.HTTPClient = client
+	return nil
+}
+
+// rateLimiter is shared by all sources so the limits hold for the whole process
+var rateLimiter *source.RateLimiter
+
+func sharedRateLimiter(cfg *HTTPConfig) (*source.RateLimiter, error) {
+	if rateLimiter != nil {
+		return rateLimiter, nil
+	}
+	path, err := dataPath(BUDGET_FILE)
+	if err != nil {
+		return nil, err
+	}
+	rateLimiter = source.NewRateLimiter(source.RateLimitOptions{
+		Rate:          cfg.RateLimit,
+		Burst:         cfg.Burst,
+		MaxConcurrent: cfg.MaxConcurrent,
+		DailyBudget:   cfg.DailyBudget,
+		BudgetFile:    path,
+	})
+	return rateLimiter, nil
+}
@@ -0,0 +1,781 @@
+package interfaces
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	DATA_REPO_DIR = "data-repo"
+	DATA_BRANCH   = "main"
+	// pushes tried when another machine pushed in between
+	DATA_SYNC_ATTEMPTS = 3
+
+	// layout of the repository, one file per record so that changes
+	// on different quotes, collections or searches never conflict
+	SYNC_ANNOTATIONS_DIR = "annotations"
+	SYNC_COLLECTIONS_DIR = "collections"
+	SYNC_LIBRARY_DIR     = "library"
+	SYNC_SEARCHES_DIR    = "searches"
+	SYNC_HISTORY_FILE    = "history.json"
+)
+
+// dataSnapshot maps the files of the repository to their content
+type dataSnapshot map[string][]byte
+
+// syncedAnnotation is the file of an annotation, it carries the quote id
+// as file names cannot hold every id
+type syncedAnnotation struct {
+	ID string `json:"id"`
+	source.Annotation
+}
+
+// syncedHistory is the file of the recent searches
+type syncedHistory struct {
+	Recent []search `json:"recent"`
+}
+
+var safeFileName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,79}$`)
+
+// syncFileName turns an id or name into the same file name on every machine
+func syncFileName(key string) string {
+	if safeFileName.MatchString(key) {
+		return key + ".json"
+	}
+	sum := sha256.Sum256([]byte(key))
+	slug := strings.TrimSuffix(source.CollectionFile(key), source.COLLECTION_EXT)
+	if len(slug) > 40 {
+		slug = slug[:40]
+	}
+	return strings.TrimPrefix(slug+"-", "-") + hex.EncodeToString(sum[:6]) + ".json"
+}
+
+// put stores v as indented JSON, the same data always gives the same bytes
+func (s dataSnapshot) put(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	s[file] = append(data, '\n')
+	return nil
+}
+
+// putAnnotations stores a file for every annotation by quote id
+func (s dataSnapshot) putAnnotations(all map[string]*source.Annotation) error {
+	for id, an := range all {
+		a := *an
+		a.UpdatedAt = a.UpdatedAt.UTC()
+		if err := s.put(path.Join(SYNC_ANNOTATIONS_DIR, syncFileName(id)), syncedAnnotation{ID: id, Annotation: a}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dir returns the files of s in the directory name
+func (s dataSnapshot) dir(name string) dataSnapshot {
+	files := dataSnapshot{}
+	for file, data := range s {
+		if path.Dir(file) == name {
+			files[file] = data
+		}
+	}
+	return files
+}
+
+// localSnapshot turns the data of this machine into repository files
+func localSnapshot() (dataSnapshot, error) {
+	snap := dataSnapshot{}
+	// read from the file, the annotations loaded by this run may be old
+	p, err := dataPath(ANNOTATIONS_FILE)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := source.OpenAnnotations(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := snap.putAnnotations(notes.All()); err != nil {
+		return nil, err
+	}
+
+	cs, err := openCollections()
+	if err != nil {
+		return nil, err
+	}
+	list, err := cs.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range list {
+		c.CreatedAt, c.UpdatedAt = c.CreatedAt.UTC(), c.UpdatedAt.UTC()
+		if err := snap.put(path.Join(SYNC_COLLECTIONS_DIR, source.CollectionFile(c.Name)), c); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := openLibrary(false)
+	if err != nil {
+		return nil, err
+	}
+	if db != nil {
+		defer db.Close()
+		for _, id := range db.IDs() {
+			q, err := db.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			q.FetchedAt = q.FetchedAt.UTC()
+			if err := snap.put(path.Join(SYNC_LIBRARY_DIR, syncFileName(id)), q); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	h, _, err := loadHistory()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range h.Saved {
+		s.RanAt = s.RanAt.UTC()
+		if err := snap.put(path.Join(SYNC_SEARCHES_DIR, syncFileName(strings.ToLower(s.Name))), s); err != nil {
+			return nil, err
+		}
+	}
+	if len(h.Recent) > 0 {
+		recent := make([]search, len(h.Recent))
+		for i, s := range h.Recent {
+			s.RanAt = s.RanAt.UTC()
+			recent[i] = s
+		}
+		if err := snap.put(SYNC_HISTORY_FILE, syncedHistory{Recent: recent}); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// openLibrary opens the library database, nil when it does not exist
+// unless create is set
+func openLibrary(create bool) (*source.LocalDB, error) {
+	p, err := dataPath(LIBRARY_DB)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(p); os.IsNotExist(err) && !create {
+		return nil, nil
+	}
+	return source.OpenLocalDB(p)
+}
+
+// syncedData is the decoded content of a snapshot, the records are keyed
+// by their file
+type syncedData struct {
+	annotations map[string]*syncedAnnotation
+	collections map[string]*source.Collection
+	library     map[string]*source.Quote
+	history     searchHistory
+}
+
+func decodeSnapshot(snap dataSnapshot) (*syncedData, error) {
+	d := &syncedData{
+		annotations: map[string]*syncedAnnotation{},
+		collections: map[string]*source.Collection{},
+		library:     map[string]*source.Quote{},
+	}
+	for file, data := range snap {
+		var err error
+		switch {
+		case strings.HasPrefix(file, SYNC_ANNOTATIONS_DIR+"/"):
+			an := &syncedAnnotation{}
+			if err = json.Unmarshal(data, an); err == nil {
+				d.annotations[file] = an
+			}
+		case strings.HasPrefix(file, SYNC_COLLECTIONS_DIR+"/"):
+			c := &source.Collection{}
+			if err = json.Unmarshal(data, c); err == nil {
+				d.collections[file] = c
+			}
+		case strings.HasPrefix(file, SYNC_LIBRARY_DIR+"/"):
+			q := &source.Quote{}
+			if err = json.Unmarshal(data, q); err == nil {
+				d.library[file] = q
+			}
+		case strings.HasPrefix(file, SYNC_SEARCHES_DIR+"/"):
+			s := search{}
+			if err = json.Unmarshal(data, &s); err == nil {
+				d.history.Saved = append(d.history.Saved, s)
+			}
+		case file == SYNC_HISTORY_FILE:
+			recent := &syncedHistory{}
+			if err = json.Unmarshal(data, recent); err == nil {
+				d.history.Recent = recent.Recent
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", file, err)
+		}
+	}
+	sort.Slice(d.history.Saved, func(i, j int) bool {
+		return strings.ToLower(d.history.Saved[i].Name) < strings.ToLower(d.history.Saved[j].Name)
+	})
+	return d, nil
+}
+
+// applySnapshot makes snap the data of this machine. The local data is
+// read again and merged with snap from base, the data that was synced, so
+// what changed here during the sync is kept. Only the records that differ
+// are written.
+func applySnapshot(base, snap dataSnapshot) error {
+	current, err := localSnapshot()
+	if err != nil {
+		return err
+	}
+	merged, _, err := mergeSnapshots(base, current, snap)
+	if err != nil {
+		return err
+	}
+	was, err := decodeSnapshot(current)
+	if err != nil {
+		return err
+	}
+	now, err := decodeSnapshot(merged)
+	if err != nil {
+		return err
+	}
+	differs := func(file string) bool {
+		c, inCurrent := current[file]
+		m, inMerged := merged[file]
+		return inCurrent != inMerged || !bytes.Equal(c, m)
+	}
+	// the directories, or "." for the history file, holding changes
+	changed := map[string]bool{}
+	for _, s := range []dataSnapshot{current, merged} {
+		for file := range s {
+			if differs(file) {
+				changed[path.Dir(file)] = true
+			}
+		}
+	}
+
+	if changed[SYNC_ANNOTATIONS_DIR] {
+		notes, err := loadAnnotations()
+		if err != nil {
+			return err
+		}
+		// merged again with the file locked, for the annotations saved
+		// by other goqu processes since current was read
+		err = notes.Update(func(quotes map[string]*source.Annotation) error {
+			stored := dataSnapshot{}
+			if err := stored.putAnnotations(quotes); err != nil {
+				return err
+			}
+			merged, _, err := mergeSnapshots(base.dir(SYNC_ANNOTATIONS_DIR), stored, snap.dir(SYNC_ANNOTATIONS_DIR))
+			if err != nil {
+				return err
+			}
+			now, err := decodeSnapshot(merged)
+			if err != nil {
+				return err
+			}
+			for id := range quotes {
+				delete(quotes, id)
+			}
+			for _, an := range now.annotations {
+				if a := an.Annotation; !a.IsEmpty() {
+					quotes[an.ID] = &a
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if changed[SYNC_COLLECTIONS_DIR] {
+		cs, err := openCollections()
+		if err != nil {
+			return err
+		}
+		for file, c := range was.collections {
+			if _, ok := now.collections[file]; !ok {
+				if err := cs.Delete(c.Name); err != nil {
+					return err
+				}
+			}
+		}
+		for file, c := range now.collections {
+			if differs(file) {
+				if err := cs.Save(c); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if changed[SYNC_LIBRARY_DIR] {
+		db, err := openLibrary(true)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		for file, q := range was.library {
+			if _, ok := now.library[file]; !ok {
+				if err := db.Delete(q.ID); err != nil && err != source.ErrQuoteNotFound {
+					return err
+				}
+			}
+		}
+		for file, q := range now.library {
+			if differs(file) {
+				if err := db.Put(q); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if changed[SYNC_SEARCHES_DIR] || changed["."] {
+		return updateHistory(func(h *searchHistory) {
+			h.Saved, h.Recent = now.history.Saved, now.history.Recent
+		})
+	}
+	return nil
+}
+
+// mergeSnapshots merges the files of two machines changed apart from
+// base and returns how many files both changed
+func mergeSnapshots(base, ours, theirs dataSnapshot) (dataSnapshot, int, error) {
+	merged := dataSnapshot{}
+	conflicts := 0
+	files := map[string]bool{}
+	for _, s := range []dataSnapshot{base, ours, theirs} {
+		for file := range s {
+			files[file] = true
+		}
+	}
+	for file := range files {
+		b, inBase := base[file]
+		o, inOurs := ours[file]
+		t, inTheirs := theirs[file]
+		same := func(x []byte, inX bool, y []byte, inY bool) bool {
+			return inX == inY && bytes.Equal(x, y)
+		}
+		switch {
+		case same(o, inOurs, t, inTheirs):
+		case same(o, inOurs, b, inBase):
+			o, inOurs = t, inTheirs
+		case same(t, inTheirs, b, inBase):
+		// changes win over a deletion on the other side
+		case !inOurs:
+			o, inOurs = t, true
+		case !inTheirs:
+		default:
+			data, err := mergeFile(file, b, o, t)
+			if err != nil {
+				return nil, 0, fmt.Errorf("could not merge %s: %v", file, err)
+			}
+			o = data
+			conflicts++
+		}
+		if inOurs {
+			merged[file] = o
+		}
+	}
+	return merged, conflicts, nil
+}
+
+// mergeFile merges the fields of a record both sides changed, base is
+// nil when both added it
+func mergeFile(file string, base, ours, theirs []byte) ([]byte, error) {
+	decode := func(v ...interface{}) error {
+		for i, data := range [][]byte{base, ours, theirs} {
+			if data == nil {
+				continue
+			}
+			if err := json.Unmarshal(data, v[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	out := dataSnapshot{}
+	var err error
+	switch {
+	case strings.HasPrefix(file, SYNC_ANNOTATIONS_DIR+"/"):
+		b, o, t := &syncedAnnotation{}, &syncedAnnotation{}, &syncedAnnotation{}
+		if err := decode(b, o, t); err != nil {
+			return nil, err
+		}
+		merged := source.MergeAnnotations(&b.Annotation, &o.Annotation, &t.Annotation)
+		err = out.put(file, syncedAnnotation{ID: o.ID, Annotation: *merged})
+	case strings.HasPrefix(file, SYNC_COLLECTIONS_DIR+"/"):
+		b, o, t := &source.Collection{}, &source.Collection{}, &source.Collection{}
+		if err := decode(b, o, t); err != nil {
+			return nil, err
+		}
+		err = out.put(file, source.MergeCollections(b, o, t))
+	case strings.HasPrefix(file, SYNC_LIBRARY_DIR+"/"):
+		b, o, t := &source.Quote{}, &source.Quote{}, &source.Quote{}
+		if err := decode(b, o, t); err != nil {
+			return nil, err
+		}
+		err = out.put(file, source.MergeQuotes(b, o, t))
+	case strings.HasPrefix(file, SYNC_SEARCHES_DIR+"/"):
+		b, o, t := &search{}, &search{}, &search{}
+		if err := decode(b, o, t); err != nil {
+			return nil, err
+		}
+		// the source and query go together, the last saved wins
+		if t.RanAt.After(o.RanAt) {
+			o = t
+		}
+		err = out.put(file, o)
+	case file == SYNC_HISTORY_FILE:
+		b, o, t := &syncedHistory{}, &syncedHistory{}, &syncedHistory{}
+		if err := decode(b, o, t); err != nil {
+			return nil, err
+		}
+		err = out.put(file, syncedHistory{Recent: mergeRecent(o.Recent, t.Recent)})
+	default:
+		return ours, nil
+	}
+	return out[file], err
+}
+
+// mergeRecent interleaves the recent searches of both sides, newest first
+func mergeRecent(ours, theirs []search) []search {
+	all := append(append([]search{}, ours...), theirs...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].RanAt.After(all[j].RanAt) })
+	h := &searchHistory{}
+	for i := len(all) - 1; i >= 0; i-- {
+		h.add(all[i])
+	}
+	return h.Recent
+}
+
+// gitRepo runs the git CLI in a repository
+type gitRepo struct {
+	ctx context.Context
+	dir string
+	env []string
+}
+
+// useDefaultIdentity commits as goqu when git has no identity configured
+func (g *gitRepo) useDefaultIdentity() {
+	if _, err := g.run("config", "user.email"); err == nil {
+		return
+	}
+	host, _ := os.Hostname()
+	g.env = []string{
+		"GIT_AUTHOR_NAME=goqu", "GIT_AUTHOR_EMAIL=goqu@" + host,
+		"GIT_COMMITTER_NAME=goqu", "GIT_COMMITTER_EMAIL=goqu@" + host,
+	}
+}
+
+func (g *gitRepo) output(args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(g.ctx, "git", args...)
+	cmd.Dir = g.dir
+	cmd.Env = append(os.Environ(), g.env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
+
+func (g *gitRepo) run(args ...string) (string, error) {
+	out, err := g.output(args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+func (g *gitRepo) hasRev(rev string) bool {
+	_, err := g.run("rev-parse", "-q", "--verify", rev+"^{commit}")
+	return err == nil
+}
+
+func (g *gitRepo) isAncestor(a, b string) bool {
+	_, err := g.run("merge-base", "--is-ancestor", a, b)
+	return err == nil
+}
+
+// commit records the staged changes
+func (g *gitRepo) commit(message string, allowEmpty bool) error {
+	args := []string{"commit", "-q", "--no-verify", "-m", message}
+	if allowEmpty {
+		args = append(args, "--allow-empty")
+	}
+	_, err := g.run(args...)
+	return err
+}
+
+// snapshot reads the files of a commit with a single git process
+func (g *gitRepo) snapshot(rev string) (dataSnapshot, error) {
+	snap := dataSnapshot{}
+	list, err := g.output("ls-tree", "-r", "-z", "--name-only", rev)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range strings.Split(string(list), "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return snap, nil
+	}
+	var in bytes.Buffer
+	for _, f := range files {
+		fmt.Fprintf(&in, "%s:%s\n", rev, f)
+	}
+	cmd := exec.CommandContext(g.ctx, "git", "cat-file", "--batch")
+	cmd.Dir = g.dir
+	cmd.Stdin = &in
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %v", err)
+	}
+	r := bufio.NewReader(bytes.NewReader(out))
+	for _, f := range files {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("git cat-file: %v", err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("git cat-file: unexpected %q", strings.TrimSpace(header))
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("git cat-file: unexpected %q", strings.TrimSpace(header))
+		}
+		data := make([]byte, size+1)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("git cat-file: %v", err)
+		}
+		snap[f] = data[:size]
+	}
+	return snap, nil
+}
+
+// writeWorktree replaces the data files of the working tree with snap
+func (g *gitRepo) writeWorktree(snap dataSnapshot) error {
+	for _, p := range []string{SYNC_ANNOTATIONS_DIR, SYNC_COLLECTIONS_DIR, SYNC_LIBRARY_DIR, SYNC_SEARCHES_DIR, SYNC_HISTORY_FILE} {
+		if err := os.RemoveAll(filepath.Join(g.dir, p)); err != nil {
+			return err
+		}
+	}
+	for file, data := range snap {
+		p := filepath.Join(g.dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge brings the commits of rev into HEAD, merging the records both
+// sides changed field by field
+func (g *gitRepo) merge(rev, host string) (int, error) {
+	if g.isAncestor(rev, "HEAD") {
+		return 0, nil
+	}
+	if g.isAncestor("HEAD", rev) {
+		_, err := g.run("merge", "-q", "--ff-only", rev)
+		return 0, err
+	}
+	base := dataSnapshot{}
+	if mergeBase, err := g.run("merge-base", "HEAD", rev); err == nil {
+		if base, err = g.snapshot(mergeBase); err != nil {
+			return 0, err
+		}
+	}
+	ours, err := g.snapshot("HEAD")
+	if err != nil {
+		return 0, err
+	}
+	theirs, err := g.snapshot(rev)
+	if err != nil {
+		return 0, err
+	}
+	merged, conflicts, err := mergeSnapshots(base, ours, theirs)
+	if err != nil {
+		return 0, err
+	}
+	// git records the merge, the content is the one merged above
+	if _, err := g.run("merge", "-q", "--no-commit", "--no-ff", "-s", "ours", "--allow-unrelated-histories", rev); err != nil {
+		return 0, err
+	}
+	if err := g.writeWorktree(merged); err != nil {
+		return 0, err
+	}
+	if _, err := g.run("add", "-A"); err != nil {
+		return 0, err
+	}
+	return conflicts, g.commit("Merge goqu data on "+host, true)
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "data",
+		usage: "sync ratings, notes, searches, collections and the library through a git repository: sync",
+		run:   runData,
+	})
+}
+
+const dataUsage = `Usage: goqu data sync [-repo dir] [-remote url] [-branch name] [-no-push]
+The remote is remembered by the repository, give -remote on the first sync only.`
+
+func runData(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Println(dataUsage)
+		return fmt.Errorf("missing subcommand")
+	}
+	sub := args[0]
+	defaultRepo, err := dataPath(DATA_REPO_DIR)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("data " + sub)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), dataUsage)
+		fs.PrintDefaults()
+	}
+	repo := fs.String("repo", defaultRepo, "local git repository holding the data")
+	remote := fs.String("remote", "", "git remote to sync with, any URL or path git accepts, e.g. a bare repository")
+	branch := fs.String("branch", DATA_BRANCH, "branch holding the data")
+	noPush := fs.Bool("no-push", false, "pull and merge, but do not push")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	switch sub {
+	case "sync":
+		return dataSync(ctx, *repo, *remote, *branch, !*noPush)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown subcommand: %s", sub)
+	}
+}
+
+// dataSync commits the local data, merges the one of the remote, pushes
+// the result and makes it the local data
+func dataSync(ctx context.Context, dir, remote, branch string, push bool) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git is required to sync: %v", err)
+	}
+	host, _ := os.Hostname()
+	g := &gitRepo{ctx: ctx, dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		if _, err := g.run("init", "-q"); err != nil {
+			return err
+		}
+		if _, err := g.run("symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+			return err
+		}
+	}
+	g.useDefaultIdentity()
+	if remote != "" {
+		_, err := g.run("remote", "get-url", "origin")
+		if err == nil {
+			_, err = g.run("remote", "set-url", "origin", remote)
+		} else {
+			_, err = g.run("remote", "add", "origin", remote)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	local, err := localSnapshot()
+	if err != nil {
+		return err
+	}
+	if err := g.writeWorktree(local); err != nil {
+		return err
+	}
+	if _, err := g.run("add", "-A"); err != nil {
+		return err
+	}
+	status, err := g.run("status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status != "" || !g.hasRev("HEAD") {
+		if err := g.commit("goqu data of "+host, true); err != nil {
+			return err
+		}
+		pterm.Info.Printfln("Committed the local changes in %s", dir)
+	}
+
+	if _, err := g.run("remote", "get-url", "origin"); err != nil {
+		pterm.Warning.Println("No remote to sync with, add one with -remote")
+		return nil
+	}
+	remoteBranch := "refs/remotes/origin/" + branch
+	for attempt := 1; ; attempt++ {
+		if _, err := g.run("fetch", "-q", "origin"); err != nil {
+			return err
+		}
+		if g.hasRev(remoteBranch) {
+			conflicts, err := g.merge(remoteBranch, host)
+			if err != nil {
+				return err
+			}
+			if conflicts > 0 {
+				pterm.Info.Printfln("Merged %d records changed on both sides", conflicts)
+			}
+		}
+		if !push {
+			break
+		}
+		_, err := g.run("push", "-q", "origin", "HEAD:refs/heads/"+branch)
+		if err == nil {
+			break
+		}
+		// another machine pushed since the fetch
+		if attempt == DATA_SYNC_ATTEMPTS {
+			return err
+		}
+	}
+
+	merged, err := g.snapshot("HEAD")
+	if err != nil {
+		return err
+	}
+	if err := applySnapshot(local, merged); err != nil {
+		return fmt.Errorf("could not update the local data: %v", err)
+	}
+	pterm.Success.Printfln("Data in sync with %s (%d files)", branch, len(merged))
+	return nil
+}
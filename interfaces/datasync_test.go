@@ -0,0 +1,135 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+func TestMergeSnapshots(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1, t2 := t0.Add(time.Hour), t0.Add(2*time.Hour)
+	note := path.Join(SYNC_ANNOTATIONS_DIR, "q1.json")
+	quote := path.Join(SYNC_LIBRARY_DIR, "q2.json")
+	other := path.Join(SYNC_COLLECTIONS_DIR, "c.json")
+
+	annotation := func(rating int, note string, updated time.Time) []byte {
+		return encodeSynced(t, syncedAnnotation{ID: "q1", Annotation: source.Annotation{Rating: rating, Note: note, UpdatedAt: updated}})
+	}
+	recent := func(searches ...search) []byte {
+		return encodeSynced(t, syncedHistory{Recent: searches})
+	}
+	s1 := search{Source: "assets", Query: source.QueryOptions{Query: "one"}, RanAt: t1}
+	s2 := search{Source: "assets", Query: source.QueryOptions{Query: "two"}, RanAt: t2}
+
+	tests := []struct {
+		name               string
+		base, ours, theirs dataSnapshot
+		want               dataSnapshot
+		conflicts          int
+	}{
+		{
+			name:   "unchanged",
+			base:   dataSnapshot{note: annotation(3, "", t0)},
+			ours:   dataSnapshot{note: annotation(3, "", t0)},
+			theirs: dataSnapshot{note: annotation(3, "", t0)},
+			want:   dataSnapshot{note: annotation(3, "", t0)},
+		},
+		{
+			name:   "changed on their side",
+			base:   dataSnapshot{note: annotation(3, "", t0)},
+			ours:   dataSnapshot{note: annotation(3, "", t0)},
+			theirs: dataSnapshot{note: annotation(5, "", t1)},
+			want:   dataSnapshot{note: annotation(5, "", t1)},
+		},
+		{
+			name:   "added on both sides to different files",
+			base:   dataSnapshot{},
+			ours:   dataSnapshot{note: annotation(3, "", t0)},
+			theirs: dataSnapshot{other: []byte("{}\n")},
+			want:   dataSnapshot{note: annotation(3, "", t0), other: []byte("{}\n")},
+		},
+		{
+			name:   "deleted on our side",
+			base:   dataSnapshot{note: annotation(3, "", t0), other: []byte("{}\n")},
+			ours:   dataSnapshot{other: []byte("{}\n")},
+			theirs: dataSnapshot{note: annotation(3, "", t0), other: []byte("{}\n")},
+			want:   dataSnapshot{other: []byte("{}\n")},
+		},
+		{
+			name:   "change wins over a deletion",
+			base:   dataSnapshot{note: annotation(3, "", t0)},
+			ours:   dataSnapshot{},
+			theirs: dataSnapshot{note: annotation(4, "", t1)},
+			want:   dataSnapshot{note: annotation(4, "", t1)},
+		},
+		{
+			name:      "fields of a record changed on both sides",
+			base:      dataSnapshot{note: annotation(3, "", t0)},
+			ours:      dataSnapshot{note: annotation(5, "", t1)},
+			theirs:    dataSnapshot{note: annotation(3, "note", t2)},
+			want:      dataSnapshot{note: annotation(5, "note", t2)},
+			conflicts: 1,
+		},
+		{
+			name:      "library quote changed on both sides",
+			base:      dataSnapshot{quote: encodeSynced(t, &source.Quote{ID: "q2", Text: "text", Author: "A"})},
+			ours:      dataSnapshot{quote: encodeSynced(t, &source.Quote{ID: "q2", Text: "longer text", Author: "A"})},
+			theirs:    dataSnapshot{quote: encodeSynced(t, &source.Quote{ID: "q2", Text: "text", Author: "B"})},
+			want:      dataSnapshot{quote: encodeSynced(t, &source.Quote{ID: "q2", Text: "longer text", Author: "B"})},
+			conflicts: 1,
+		},
+		{
+			name:      "recent searches interleaved",
+			base:      dataSnapshot{},
+			ours:      dataSnapshot{SYNC_HISTORY_FILE: recent(s1)},
+			theirs:    dataSnapshot{SYNC_HISTORY_FILE: recent(s2)},
+			want:      dataSnapshot{SYNC_HISTORY_FILE: recent(s2, s1)},
+			conflicts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts, err := mergeSnapshots(tt.base, tt.ours, tt.theirs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(snapshotText(got), snapshotText(tt.want)) {
+				t.Errorf("mergeSnapshots() = %v, want %v", snapshotText(got), snapshotText(tt.want))
+			}
+			if conflicts != tt.conflicts {
+				t.Errorf("conflicts = %d, want %d", conflicts, tt.conflicts)
+			}
+		})
+	}
+}
+
+// encodeSynced writes v as localSnapshot does
+func encodeSynced(t *testing.T, v interface{}) []byte {
+	snap := dataSnapshot{}
+	if err := snap.put("f", v); err != nil {
+		t.Fatal(err)
+	}
+	return snap["f"]
+}
+
+// snapshotText makes snapshots comparable and readable in failures
+func snapshotText(snap dataSnapshot) []string {
+	var files []string
+	for file, data := range snap {
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			files = append(files, file+": "+string(data))
+			continue
+		}
+		canonical, _ := json.Marshal(v)
+		files = append(files, file+": "+string(canonical))
+	}
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,121 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/export"
+	"github.com/custompointofview/goqu/source"
+)
+
+type exporter func(path string, quotes []*source.Quote, opts export.Options) error
+
+var exporters = map[string]exporter{
+	"fortune": func(path string, quotes []*source.Quote, opts export.Options) error {
+		return source.WriteFortune(path, quotes)
+	},
+	"markdown": exportToFile(export.Markdown),
+	"html":     exportToFile(export.HTML),
+	"epub":     exportToFile(export.EPUB),
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "export",
+		usage: "export the quotes matching a query to a file",
+		run:   runExport,
+	})
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := newFlagSet("export")
+	srcName := fs.String("source", "quotegarden", SOURCE_USAGE)
+	format := fs.String("format", "fortune", "output format: "+strings.Join(exporterNames(), ", "))
+	output := fs.String("o", "", "output file")
+	selection := addQueryFlags(fs)
+	max := fs.Int("max", 0, "maximum number of quotes, 0 for all")
+	title := fs.String("title", export.DEFAULT_TITLE, "document title")
+	group := fs.String("group", export.GROUP_NONE, "group quotes by author or genre")
+	lang := fs.String("lang", "", "language of the document, e.g. de, by default the one of the quotes or "+export.DEFAULT_LANGUAGE)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	write, ok := exporters[*format]
+	if !ok {
+		return fmt.Errorf("unknown export format: %s", *format)
+	}
+	if *output == "" {
+		fs.Usage()
+		return fmt.Errorf("an output file is required")
+	}
+	qo, err := selection.options()
+	if err != nil {
+		return err
+	}
+
+	src, err := openSource(*srcName)
+	if err != nil {
+		return err
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	qo.Limit = 100
+	quotes, truncated, err := source.CollectQuotes(ctx, queryable(src), qo, *max)
+	if err != nil {
+		return fmt.Errorf("could not query source: %v", err)
+	}
+	if truncated {
+		warnTruncated(false)
+	}
+	if err := write(*output, quotes, export.Options{Title: *title, GroupBy: *group, Language: *lang}); err != nil {
+		return err
+	}
+	pterm.Success.Printfln("%d quotes exported to %s", len(quotes), *output)
+	return nil
+}
+
+func exportToFile(write func(w io.Writer, quotes []*source.Quote, opts export.Options) error) exporter {
+	return func(path string, quotes []*source.Quote, opts export.Options) error {
+		// a failed export must not leave a truncated file behind
+		f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		if err := write(f, quotes, opts); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+		if err := f.Close(); err != nil {
+			os.Remove(f.Name())
+			return err
+		}
+		if err := os.Chmod(f.Name(), 0o644); err != nil {
+			os.Remove(f.Name())
+			return err
+		}
+		if err := os.Rename(f.Name(), path); err != nil {
+			os.Remove(f.Name())
+			return err
+		}
+		return nil
+	}
+}
+
+func exporterNames() []string {
+	var names []string
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,141 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const EXTERNAL_SOURCES = "sources.json"
+
+type externalSource struct {
+	Command []string `json:"command"`
+	Env     []string `json:"env,omitempty"`
+}
+
+type externalRegistry struct {
+	Sources map[string]*externalSource `json:"sources"`
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "source",
+		usage: "register external command sources: add <name> <command> [args...], remove <name>, list",
+		run:   runSource,
+	})
+}
+
+func loadExternalRegistry() (*externalRegistry, string, error) {
+	path, err := dataPath(EXTERNAL_SOURCES)
+	if err != nil {
+		return nil, "", err
+	}
+	reg := &externalRegistry{}
+	if err := readJSON(path, reg); err != nil {
+		return nil, "", fmt.Errorf("could not read %s: %v", path, err)
+	}
+	if reg.Sources == nil {
+		reg.Sources = map[string]*externalSource{}
+	}
+	return reg, path, nil
+}
+
+// openExternal returns the registered external source called name
+func openExternal(name string) (source.Sources, bool, error) {
+	reg, _, err := loadExternalRegistry()
+	if err != nil {
+		return nil, false, err
+	}
+	ext, ok := reg.Sources[name]
+	if !ok {
+		return nil, false, nil
+	}
+	return source.NewExternal(name, ext.Command, ext.Env), true, nil
+}
+
+func externalNames() []string {
+	reg, _, err := loadExternalRegistry()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for name := range reg.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runSource(ctx context.Context, args []string) error {
+	fs := newFlagSet("source")
+	var env stringList
+	fs.Var(&env, "env", "KEY=value passed to the command, repeatable")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: goqu source add [-env KEY=value] <name> <command> [args...]\n       goqu source remove <name>\n       goqu source list")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing subcommand")
+	}
+	sub := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	reg, path, err := loadExternalRegistry()
+	if err != nil {
+		return err
+	}
+	switch sub {
+	case "add":
+		if fs.NArg() < 2 {
+			fs.Usage()
+			return fmt.Errorf("expected a name and a command")
+		}
+		name := fs.Arg(0)
+		if isBuiltinSource(name) {
+			return fmt.Errorf("%s is a built-in source", name)
+		}
+		reg.Sources[name] = &externalSource{Command: fs.Args()[1:], Env: env}
+		if err := writeJSON(path, reg); err != nil {
+			return err
+		}
+		pterm.Success.Printfln("Source %s registered, use it with -source %s", name, name)
+	case "remove":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected a name")
+		}
+		if _, ok := reg.Sources[fs.Arg(0)]; !ok {
+			return fmt.Errorf("unknown source: %s", fs.Arg(0))
+		}
+		delete(reg.Sources, fs.Arg(0))
+		return writeJSON(path, reg)
+	case "list":
+		for _, name := range externalNames() {
+			fmt.Printf("%s\t%s\n", name, strings.Join(reg.Sources[name].Command, " "))
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown subcommand: %s", sub)
+	}
+	return nil
+}
+
+// stringList collects a repeatable flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,76 @@
+package interfaces
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	CACHE_FILE = "cache.json"
+	// how often watch retries a failed source in the background
+	FAILOVER_PROBE_INTERVAL = time.Minute
+)
+
+var DEFAULT_FALLBACKS = []string{"cache", "mirror", "assets"}
+
+// withFailover protects a remote source with the configured fallbacks
+func withFailover(name string, src source.Sources) (source.Sources, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	names := cfg.Failover.Fallbacks
+	if names == nil {
+		names = DEFAULT_FALLBACKS
+	}
+	if len(names) == 1 && names[0] == "none" {
+		return src, nil
+	}
+
+	options := source.FailoverOptions{Failures: cfg.Failover.Failures}
+	if cfg.Failover.Cooldown != "" {
+		if options.Cooldown, err = time.ParseDuration(cfg.Failover.Cooldown); err != nil {
+			return nil, fmt.Errorf("invalid failover cooldown: %v", err)
+		}
+	}
+	var fallbacks []source.Member
+	for _, fb := range names {
+		if fb == "cache" {
+			path, err := dataPath(CACHE_FILE)
+			if err != nil {
+				return nil, err
+			}
+			if options.Cache, err = source.OpenCache(path, source.DEFAULT_CACHE_SIZE); err != nil {
+				return nil, fmt.Errorf("could not read the quote cache: %v", err)
+			}
+			continue
+		}
+		if fb == "mirror" {
+			// the mirror only exists once synced, not having one is fine
+			if path, err := dataPath("mirror", source.MIRROR_DB); err != nil || !fileExists(path) {
+				continue
+			}
+		}
+		fallback, err := openBareSource(fb)
+		if err != nil {
+			return nil, fmt.Errorf("fallback %s: %v", fb, err)
+		}
+		fallbacks = append(fallbacks, source.Member{Name: fb, Source: fallback})
+	}
+
+	failover := source.NewFailover(source.Member{Name: name, Source: src}, fallbacks, options)
+	failover.Notify = func(message string) {
+		pterm.Warning.Println(message)
+	}
+	return failover, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,101 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+// POST_LENGTH is the usual length limit of a social media post
+const POST_LENGTH = 280
+
+// selectConstraint adds a search term, a length constraint or an annotation filter to qo
+func (t *Term) selectConstraint(ctx context.Context, qo *source.QueryOptions) {
+	items := []string{"Search term", "Length in characters", "Length in words", "Lines on screen", "Fits in a post", "Ratings, tags and notes", GO_BACK}
+	prompt := promptui.Select{
+		Label: "Add a filter",
+		Items: items,
+	}
+	_, result, err := prompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+
+	if result == items[0] {
+		t.selectSearch(ctx, qo)
+		return
+	}
+	qoTemp := &source.QueryOptions{}
+	if qo != nil {
+		*qoTemp = *qo
+		qoTemp.Page, qoTemp.Limit = 0, 0
+	}
+	switch result {
+	case items[1]:
+		qoTemp.MinChars, qoTemp.MaxChars, err = promptRange("Characters (min-max):")
+	case items[2]:
+		qoTemp.MinWords, qoTemp.MaxWords, err = promptRange("Words (min-max):")
+	case items[3]:
+		qoTemp.MaxLines, err = promptNumber("At most lines:", 1, 100)
+	case items[4]:
+		qoTemp.FitsIn, err = promptNumber(fmt.Sprintf("Post length (e.g. %d):", POST_LENGTH), 1, 100000)
+	case items[5]:
+		err = t.selectAnnotationFilter(qoTemp)
+	case GO_BACK:
+		return
+	}
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+	t.goFurther(ctx, qoTemp)
+}
+
+// promptRange asks for a range written "min-max", either end may be left out
+func promptRange(label string) (int, int, error) {
+	prompt := promptui.Prompt{
+		Label: label,
+		Validate: func(input string) error {
+			_, _, err := parseRange(input)
+			return err
+		},
+	}
+	result, err := prompt.Run()
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseRange(result)
+}
+
+// parseRange reads "min-max", "min-" or "-max", 0 stands for no bound
+func parseRange(input string) (int, int, error) {
+	parts := strings.SplitN(strings.TrimSpace(input), "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected min-max")
+	}
+	var bounds [2]int
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid number %q", p)
+		}
+		bounds[i] = n
+	}
+	if bounds[0] == 0 && bounds[1] == 0 {
+		return 0, 0, fmt.Errorf("expected min-max")
+	}
+	if bounds[1] > 0 && bounds[0] > bounds[1] {
+		return 0, 0, fmt.Errorf("min is above max")
+	}
+	return bounds[0], bounds[1], nil
+}
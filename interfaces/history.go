@@ -0,0 +1,276 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	HISTORY_FILE = "history.json"
+	// at most this many recent searches are kept
+	HISTORY_SIZE = 30
+)
+
+// search is a query run on a named source, see openSource
+type search struct {
+	Name   string              `json:"name,omitempty"`
+	Source string              `json:"source"`
+	Query  source.QueryOptions `json:"query"`
+	RanAt  time.Time           `json:"ranAt"`
+}
+
+func (s *search) String() string {
+	text := describeQuery(&s.Query)
+	if text == "" {
+		text = "all quotes"
+	}
+	if s.Source != "" && s.Source != "quotegarden" {
+		text += " (" + s.Source + ")"
+	}
+	if s.Name != "" {
+		text = s.Name + ": " + text
+	}
+	return text
+}
+
+func (s *search) same(other *search) bool {
+	return s.Source == other.Source && reflect.DeepEqual(s.Query, other.Query)
+}
+
+// searchHistory holds the recent searches, newest first, and the saved ones
+type searchHistory struct {
+	Recent []search `json:"recent"`
+	Saved  []search `json:"saved"`
+}
+
+func loadHistory() (*searchHistory, string, error) {
+	path, err := dataPath(HISTORY_FILE)
+	if err != nil {
+		return nil, "", err
+	}
+	h := &searchHistory{}
+	if err := readJSON(path, h); err != nil {
+		return nil, "", fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return h, path, nil
+}
+
+// add puts s first in the recent searches, dropping an older identical one
+func (h *searchHistory) add(s search) {
+	recent := []search{s}
+	for _, r := range h.Recent {
+		if !r.same(&s) && len(recent) < HISTORY_SIZE {
+			recent = append(recent, r)
+		}
+	}
+	h.Recent = recent
+}
+
+// save pins s under its name, replacing a saved search of the same name
+func (h *searchHistory) save(s search) {
+	for i, saved := range h.Saved {
+		if strings.EqualFold(saved.Name, s.Name) {
+			h.Saved[i] = s
+			return
+		}
+	}
+	h.Saved = append(h.Saved, s)
+}
+
+func (h *searchHistory) find(name string) (*search, bool) {
+	for i := range h.Saved {
+		if strings.EqualFold(h.Saved[i].Name, name) {
+			return &h.Saved[i], true
+		}
+	}
+	return nil, false
+}
+
+// newSearch strips the paging from qo
+func newSearch(srcName string, qo *source.QueryOptions) search {
+	query := *qo
+	query.Page, query.Limit = 0, 0
+	// random sorts without a -seed shuffle again when run again
+	if query.SeedPicked {
+		query.Seed, query.SeedPicked = 0, false
+	}
+	return search{Source: srcName, Query: query, RanAt: time.Now()}
+}
+
+// updateHistory applies change to the history file, locked against the
+// other goqu processes recording their searches
+func updateHistory(change func(h *searchHistory)) error {
+	path, err := dataPath(HISTORY_FILE)
+	if err != nil {
+		return err
+	}
+	unlock, err := source.LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	h, _, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	change(h)
+	return writeJSON(path, h)
+}
+
+// recordSearch adds a search to the history, failing quietly: the
+// history must not get in the way of the quotes
+func recordSearch(srcName string, qo *source.QueryOptions) {
+	updateHistory(func(h *searchHistory) {
+		h.add(newSearch(srcName, qo))
+	})
+}
+
+// describeQuery sums up the options for menus and titles
+func describeQuery(qo *source.QueryOptions) string {
+	selection := qo.Selection()
+	if strings.TrimSpace(qo.Query) != "" {
+		selection = append(selection, qo.Query)
+	}
+	selection = append(selection, qo.Constraints()...)
+	if qo.Sort != "" {
+		selection = append(selection, "sorted by "+qo.Sort)
+	}
+	return strings.Join(selection, " & ")
+}
+
+// recentSearches re-runs a saved or recent search
+func (t *Term) recentSearches(ctx context.Context) {
+	h, _, err := loadHistory()
+	if err != nil {
+		pterm.Error.Println(err)
+		return
+	}
+	searches := append(append([]search{}, h.Saved...), h.Recent...)
+	if len(searches) == 0 {
+		pterm.Info.Println("No searches yet")
+		return
+	}
+	items := make([]string, 0, len(searches)+1)
+	for i := range searches {
+		mark := "  "
+		if i < len(h.Saved) {
+			mark = "★ "
+		}
+		items = append(items, mark+searches[i].String())
+	}
+	items = append(items, GO_BACK)
+	prompt := promptui.Select{
+		Label: "Recent searches",
+		Items: items,
+		Size:  10,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+	if i == len(searches) {
+		return
+	}
+
+	s := searches[i]
+	if s.Source != "" && s.Source != t.sourceName {
+		if !t.useSource(s.Source) {
+			return
+		}
+		pterm.Info.Printfln("Source changed to %s", s.Source)
+	}
+	qo := s.Query
+	reseed(&qo)
+	t.goFurther(ctx, &qo)
+}
+
+// saveSearch names and pins the search
+func (t *Term) saveSearch(qo *source.QueryOptions) {
+	prompt := promptui.Prompt{
+		Label: "Name:",
+		Validate: func(input string) error {
+			if strings.TrimSpace(input) == "" {
+				return fmt.Errorf("name must not be empty")
+			}
+			return nil
+		},
+	}
+	name, err := prompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+	err = updateHistory(func(h *searchHistory) {
+		s := newSearch(t.sourceName, qo)
+		s.Name = strings.TrimSpace(name)
+		h.save(s)
+	})
+	if err != nil {
+		pterm.Error.Printfln("could not save search: %v", err)
+		return
+	}
+	pterm.Success.Printfln("Saved, run it again with: goqu run-saved %q", strings.TrimSpace(name))
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "run-saved",
+		usage: "print the quotes of a search saved in the terminal UI, without a name list the saved searches",
+		run:   runSaved,
+	})
+}
+
+func runSaved(ctx context.Context, args []string) error {
+	fs := newFlagSet("run-saved")
+	srcName := fs.String("source", "", "source to run the search on instead of its own")
+	max := fs.Int("max", DEFAULT_LIST_MAX, "maximum number of quotes, 0 for all")
+	plain := fs.Bool("plain", false, "one quote per line, for scripts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	h, _, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		if len(h.Saved) == 0 {
+			return fmt.Errorf("no saved search, save one with \"Save this search\" in goqu")
+		}
+		for _, s := range h.Saved {
+			fmt.Println(s.String())
+		}
+		return nil
+	}
+	name := strings.Join(fs.Args(), " ")
+	s, ok := h.find(name)
+	if !ok {
+		return fmt.Errorf("no saved search named %q", name)
+	}
+
+	if *srcName == "" {
+		*srcName = s.Source
+	}
+	src, err := openSource(*srcName)
+	if err != nil {
+		return err
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if *plain {
+		silence(src)
+	}
+	reseed(&s.Query)
+	return printQuotes(ctx, src, &s.Query, *max, *plain, false)
+}
@@ -0,0 +1,163 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+var importers = map[string]func(r io.Reader) ([]*source.Quote, error){
+	"kindle": source.ParseKindleClippings,
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "import",
+		usage: "import quotes into your library, e.g. `goqu import kindle \"My Clippings.txt\"`",
+		run:   runImport,
+	})
+}
+
+func runImport(ctx context.Context, args []string) error {
+	defaultDB, err := dataPath(LIBRARY_DB)
+	if err != nil {
+		return err
+	}
+
+	fs := newFlagSet("import")
+	dbPath := fs.String("db", defaultDB, "database to import into")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: goqu import [flags] <%s> <file>\n", strings.Join(importerNames(), "|"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected a format and a file")
+	}
+	parse, ok := importers[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown import format: %s", fs.Arg(0))
+	}
+
+	f, err := os.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	quotes, err := parse(f)
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %v", fs.Arg(1), err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*dbPath), 0o755); err != nil {
+		return err
+	}
+	db, err := source.OpenLocalDB(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	library, err := quotesByWork(db)
+	if err != nil {
+		return err
+	}
+	added, updated := 0, 0
+	for _, q := range quotes {
+		if old, err := db.Get(q.ID); err == nil {
+			// the same highlight, extended since the last import
+			if old.Text != q.Text {
+				if err := db.Put(q); err != nil {
+					return err
+				}
+				// later clippings of the work compare with the new text
+				library[workKey(old)] = withoutQuote(library[workKey(old)], old.ID)
+				library[workKey(q)] = append(library[workKey(q)], q)
+				updated++
+			}
+			continue
+		}
+		key := workKey(q)
+		known, replaced := false, false
+		var kept []*source.Quote
+		for _, old := range library[key] {
+			switch {
+			case strings.Contains(old.Text, q.Text):
+				known = true
+				kept = append(kept, old)
+			case strings.Contains(q.Text, old.Text):
+				// a shorter version imported before under another id
+				if err := db.Delete(old.ID); err != nil && err != source.ErrQuoteNotFound {
+					return err
+				}
+				replaced = true
+			default:
+				kept = append(kept, old)
+			}
+		}
+		library[key] = kept
+		if known {
+			continue
+		}
+		if err := db.Put(q); err != nil {
+			return err
+		}
+		library[key] = append(library[key], q)
+		if replaced {
+			updated++
+		} else {
+			added++
+		}
+	}
+	pterm.Success.Printfln("%d quotes found, %d new quotes imported into %s, %d updated", len(quotes), added, *dbPath, updated)
+	return nil
+}
+
+// workKey groups the quotes of a provider by book and author
+func workKey(q *source.Quote) string {
+	return q.Provider + "\x00" + q.Work + "\x00" + q.Author
+}
+
+// quotesByWork loads the library grouped by workKey
+func quotesByWork(db *source.LocalDB) (map[string][]*source.Quote, error) {
+	library := map[string][]*source.Quote{}
+	for _, id := range db.IDs() {
+		q, err := db.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		library[workKey(q)] = append(library[workKey(q)], q)
+	}
+	return library, nil
+}
+
+// withoutQuote returns quotes without the quote of that id
+func withoutQuote(quotes []*source.Quote, id string) []*source.Quote {
+	var kept []*source.Quote
+	for _, q := range quotes {
+		if q.ID != id {
+			kept = append(kept, q)
+		}
+	}
+	return kept
+}
+
+func importerNames() []string {
+	var names []string
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
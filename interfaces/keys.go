@@ -0,0 +1,233 @@
+package interfaces
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	KEYS_DIR          = "keys"
+	SIGNING_KEY_FILE  = "bundle.key"
+	TRUSTED_KEYS_FILE = "trusted_keys.json"
+	// name under which `goqu key generate` trusts the new key
+	OWN_KEY_NAME = "me"
+)
+
+// trustedKey is a public key whose signed bundles are accepted
+type trustedKey struct {
+	Name      string    `json:"name"`
+	KeyID     string    `json:"keyId"`
+	PublicKey string    `json:"publicKey"`
+	AddedAt   time.Time `json:"addedAt"`
+}
+
+type trustedKeys struct {
+	Keys []trustedKey `json:"keys"`
+}
+
+func loadTrustedKeys() (*trustedKeys, string, error) {
+	path, err := dataPath(TRUSTED_KEYS_FILE)
+	if err != nil {
+		return nil, "", err
+	}
+	tk := &trustedKeys{}
+	if err := readJSON(path, tk); err != nil {
+		return nil, "", fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return tk, path, nil
+}
+
+// trust adds pub under name, a key can only be trusted once
+func (tk *trustedKeys) trust(name string, pub ed25519.PublicKey) error {
+	encoded := source.EncodePublicKey(pub)
+	for _, k := range tk.Keys {
+		if strings.EqualFold(k.Name, name) {
+			return fmt.Errorf("a key named %q is trusted already", name)
+		}
+		if k.PublicKey == encoded {
+			return fmt.Errorf("key %s is trusted already as %q", k.KeyID, k.Name)
+		}
+	}
+	tk.Keys = append(tk.Keys, trustedKey{Name: name, KeyID: source.KeyID(pub), PublicKey: encoded, AddedAt: time.Now()})
+	return nil
+}
+
+// untrust removes the key of that name or id
+func (tk *trustedKeys) untrust(nameOrID string) error {
+	for i, k := range tk.Keys {
+		if strings.EqualFold(k.Name, nameOrID) || k.KeyID == nameOrID {
+			tk.Keys = append(tk.Keys[:i], tk.Keys[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no trusted key %q", nameOrID)
+}
+
+// signer returns the trusted key that made sig, nil when it is unknown.
+// The keys are compared decoded, a signature may hold a PEM key.
+func (tk *trustedKeys) signer(sig *source.Signature) *trustedKey {
+	pub, err := source.DecodePublicKey(sig.PublicKey)
+	if err != nil {
+		return nil
+	}
+	for i, k := range tk.Keys {
+		if trusted, err := source.DecodePublicKey(k.PublicKey); err == nil && trusted.Equal(pub) {
+			return &tk.Keys[i]
+		}
+	}
+	return nil
+}
+
+func defaultSigningKey() string {
+	path, err := dataPath(KEYS_DIR, SIGNING_KEY_FILE)
+	if err != nil {
+		return SIGNING_KEY_FILE
+	}
+	return path
+}
+
+// checkBundleSigner tells who signed a bundle and refuses it when
+// requireTrusted is set and the signer is missing or unknown. Once a key
+// is trusted, unsigned bundles and unknown signers are refused unless
+// allowUntrusted is set: removing manifest.sig or signing a changed bundle
+// again with another key must not turn it into a mere warning.
+func checkBundleSigner(manifest *source.Manifest, requireTrusted, allowUntrusted bool) error {
+	tk, _, err := loadTrustedKeys()
+	if err != nil {
+		return err
+	}
+	sig := manifest.Signature
+	if sig == nil {
+		if requireTrusted {
+			return fmt.Errorf("the bundle is not signed")
+		}
+		if len(tk.Keys) > 0 && !allowUntrusted {
+			return fmt.Errorf("the bundle is not signed, its signature may have been removed; import it with -allow-untrusted if you trust where it comes from")
+		}
+		pterm.Warning.Println("The bundle is not signed, its origin cannot be checked")
+		return nil
+	}
+	if k := tk.signer(sig); k != nil {
+		pterm.Success.Printfln("Signed by %s (key %s)", k.Name, k.KeyID)
+		return nil
+	}
+	if requireTrusted {
+		return fmt.Errorf("the bundle is signed by key %s, which is not trusted", sig.KeyID)
+	}
+	if len(tk.Keys) > 0 && !allowUntrusted {
+		return fmt.Errorf("the bundle is signed by key %s, which is not trusted; once its owner confirms the key, trust it with goqu key trust <name> %s, or import it with -allow-untrusted",
+			sig.KeyID, sig.PublicKey)
+	}
+	pterm.Warning.Printfln("The bundle is signed by key %s, which is not trusted. Once its owner confirms the key, trust it with:\n  goqu key trust <name> %s",
+		sig.KeyID, sig.PublicKey)
+	return nil
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "key",
+		usage: "manage the keys signing bundles: generate, show, trust, untrust, list",
+		run:   runKey,
+	})
+}
+
+const keyUsage = `Usage: goqu key generate [-key file]
+       goqu key show [-key file]
+       goqu key trust <name> <public key or .pub file>
+       goqu key untrust <name or key id>
+       goqu key list
+Sign bundles with goqu collection export -sign.`
+
+func runKey(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Println(keyUsage)
+		return fmt.Errorf("missing subcommand")
+	}
+	sub := args[0]
+	fs := newFlagSet("key " + sub)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), keyUsage)
+		fs.PrintDefaults()
+	}
+	var keyFile *string
+	switch sub {
+	case "generate", "show":
+		keyFile = fs.String("key", defaultSigningKey(), "private key file, its public key is next to it with a .pub extension")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	tk, path, err := loadTrustedKeys()
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "generate":
+		pub, err := source.GenerateKey(*keyFile)
+		if err != nil {
+			return fmt.Errorf("could not generate key: %v", err)
+		}
+		pterm.Success.Printfln("Key %s written to %s", source.KeyID(pub), *keyFile)
+		// your own bundles are trusted on import
+		if err := tk.trust(OWN_KEY_NAME, pub); err != nil {
+			pterm.Warning.Printfln("%v, not trusting the new key", err)
+		} else if err := writeJSON(path, tk); err != nil {
+			return err
+		}
+		fmt.Println("Share the public key with the people importing your bundles:")
+		fmt.Println(source.EncodePublicKey(pub))
+	case "show":
+		key, err := source.LoadPrivateKey(*keyFile)
+		if err != nil {
+			return err
+		}
+		pub := key.Public().(ed25519.PublicKey)
+		fmt.Printf("%s\t%s\n", source.KeyID(pub), source.EncodePublicKey(pub))
+	case "trust":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return fmt.Errorf("expected a name and a public key")
+		}
+		text := fs.Arg(1)
+		if data, err := os.ReadFile(text); err == nil {
+			text = string(data)
+		}
+		pub, err := source.DecodePublicKey(text)
+		if err != nil {
+			return err
+		}
+		if err := tk.trust(fs.Arg(0), pub); err != nil {
+			return err
+		}
+		if err := writeJSON(path, tk); err != nil {
+			return err
+		}
+		pterm.Success.Printfln("Bundles signed by key %s are trusted as %s", source.KeyID(pub), fs.Arg(0))
+	case "untrust":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected a name or key id")
+		}
+		if err := tk.untrust(fs.Arg(0)); err != nil {
+			return err
+		}
+		return writeJSON(path, tk)
+	case "list":
+		for _, k := range tk.Keys {
+			fmt.Printf("%s\t%s\t%s\n", k.Name, k.KeyID, k.PublicKey)
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown subcommand: %s", sub)
+	}
+	return nil
+}
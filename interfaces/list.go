@@ -0,0 +1,94 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const DEFAULT_LIST_MAX = 10
+
+func init() {
+	commands = append(commands, command{
+		name:  "list",
+		usage: "print the quotes matching a query, e.g. `goqu list -genre life -sort length`",
+		run:   runList,
+	})
+}
+
+func runList(ctx context.Context, args []string) error {
+	fs := newFlagSet("list")
+	srcName := fs.String("source", "quotegarden", SOURCE_USAGE)
+	selection := addQueryFlags(fs)
+	max := fs.Int("max", DEFAULT_LIST_MAX, "maximum number of quotes, 0 for all")
+	plain := fs.Bool("plain", false, "one quote per line, for scripts")
+	ids := fs.Bool("ids", false, "start the plain lines with the quote id, e.g. for goqu annotate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	qo, err := selection.options()
+	if err != nil {
+		return err
+	}
+
+	src, err := openSource(*srcName)
+	if err != nil {
+		return err
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if *plain {
+		silence(src)
+	}
+	recordSearch(*srcName, qo)
+	return printQuotes(ctx, src, qo, *max, *plain, *ids)
+}
+
+// printQuotes collects and prints the quotes matching qo, ids prefixes the plain lines
+func printQuotes(ctx context.Context, src source.Sources, qo *source.QueryOptions, max int, plain, ids bool) error {
+	opt := *qo
+	opt.Limit = 100
+	quotes, truncated, err := source.CollectQuotes(ctx, queryable(src), &opt, max)
+	if err != nil {
+		return fmt.Errorf("could not query source: %v", err)
+	}
+	if truncated {
+		warnTruncated(plain)
+	}
+	if len(quotes) == 0 {
+		return fmt.Errorf("no quote matches the selection")
+	}
+	for _, q := range quotes {
+		if !plain {
+			q.Print()
+			continue
+		}
+		line := strings.Join(strings.Fields(q.Text), " ")
+		if attribution := q.Attribution(); attribution != "" {
+			line += " — " + attribution
+		}
+		if ids {
+			line = q.ID + "\t" + line
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// warnTruncated tells that a source stopped reading before the end of the
+// selection, on stderr for the plain output read by scripts
+func warnTruncated(plain bool) {
+	const warning = "The source was only read in part, more quotes may match and the order only covers the quotes read. Narrow the selection to see them all."
+	if plain {
+		fmt.Fprintln(os.Stderr, "goqu:", warning)
+		return
+	}
+	pterm.Warning.Println(warning)
+}
@@ -0,0 +1,186 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	DEFAULT_MOTD_BUDGET = 150 * time.Millisecond
+	MOTD_CACHE          = "motd-cache.json"
+	// cached quotes kept for offline logins
+	MOTD_CACHE_SIZE = 50
+)
+
+type motdCache struct {
+	Quotes []*source.Quote `json:"quotes"`
+	Next   int             `json:"next"`
+}
+
+func init() {
+	commands = append(commands, command{
+		name:  "motd",
+		usage: "print one quote within a strict time budget, for shell logins",
+		run:   runMotd,
+	})
+}
+
+func runMotd(ctx context.Context, args []string) error {
+	cachePath, err := dataPath(MOTD_CACHE)
+	if err != nil {
+		return err
+	}
+
+	fs := newFlagSet("motd")
+	srcName := fs.String("source", "quotegarden", SOURCE_USAGE)
+	budget := fs.Duration("budget", DEFAULT_MOTD_BUDGET, "time allowed for the source before falling back to the cache")
+	plain := fs.Bool("plain", false, "plain text without colours")
+	color := fs.Bool("color", false, "force colours even when not writing to a terminal")
+	refresh := fs.Bool("refresh", false, "only pre-fetch quotes into the cache, e.g. from cron")
+	count := fs.Int("count", 10, "quotes to pre-fetch with -refresh")
+	author := fs.String("author", "", "only quotes by this author")
+	genre := fs.String("genre", "", "only quotes of this genre")
+	query := fs.String("query", "", "only quotes containing this term")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	qo := &source.QueryOptions{Author: *author, Genre: *genre, Query: *query}
+
+	cache := &motdCache{}
+	if *refresh {
+		if err := readJSON(cachePath, cache); err != nil {
+			return err
+		}
+		picker, closeSource, err := motdPicker(*srcName, qo)
+		if err != nil {
+			return err
+		}
+		defer closeSource()
+		for i := 0; i < *count; i++ {
+			q, err := picker.next(ctx)
+			if err != nil {
+				return err
+			}
+			cache.add(q)
+		}
+		return writeJSON(cachePath, cache)
+	}
+
+	q := motdFetch(ctx, *srcName, qo, *budget)
+	fromSource := q != nil
+	if !fromSource {
+		// a broken cache must not break the login
+		if readJSON(cachePath, cache) == nil {
+			q = cache.next()
+		}
+	}
+	if q == nil {
+		q, _ = source.NewStatic(assetQuotes()).RandomQuote(ctx)
+	}
+
+	useColor := *color || (!*plain && isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "")
+	printMotd(q, useColor)
+
+	if fromSource {
+		readJSON(cachePath, cache)
+		cache.add(q)
+	}
+	// remember the rotation even when the quote came from the cache
+	writeJSON(cachePath, cache)
+	return nil
+}
+
+// motdFetch asks the source for a quote and gives up when the budget is spent
+func motdFetch(ctx context.Context, srcName string, qo *source.QueryOptions, budget time.Duration) *source.Quote {
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	result := make(chan *source.Quote, 1)
+	go func() {
+		picker, closeSource, err := motdPicker(srcName, qo)
+		if err != nil {
+			result <- nil
+			return
+		}
+		defer closeSource()
+		q, _ := picker.next(ctx)
+		result <- q
+	}()
+
+	select {
+	case q := <-result:
+		return q
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func motdPicker(srcName string, qo *source.QueryOptions) (*quotePicker, func(), error) {
+	src, err := openSource(srcName)
+	if err != nil {
+		return nil, nil, err
+	}
+	silence(src)
+	closeSource := func() {
+		if closer, ok := src.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return newQuotePicker(src, qo), closeSource, nil
+}
+
+func printMotd(q *source.Quote, useColor bool) {
+	// wrap every line on its own to keep the line breaks of poems
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(q.Text), "\n") {
+		lines = append(lines, pterm.DefaultParagraph.WithMaxWidth(72).Sprint(line))
+	}
+	text := strings.Join(lines, "\n")
+	attribution := q.Attribution()
+	if !useColor {
+		fmt.Println(text)
+		if attribution != "" {
+			fmt.Printf("    -- %s\n", attribution)
+		}
+		return
+	}
+	fmt.Println(pterm.NewStyle(pterm.FgLightCyan).Sprint(text))
+	if attribution != "" {
+		fmt.Println(pterm.NewStyle(pterm.FgGray, pterm.Italic).Sprintf("    — %s", attribution))
+	}
+}
+
+func (c *motdCache) add(q *source.Quote) {
+	for _, cached := range c.Quotes {
+		if cached.ID == q.ID && q.ID != "" {
+			return
+		}
+	}
+	c.Quotes = append(c.Quotes, q)
+	if len(c.Quotes) > MOTD_CACHE_SIZE {
+		c.Quotes = c.Quotes[len(c.Quotes)-MOTD_CACHE_SIZE:]
+	}
+}
+
+// next rotates through the cached quotes
+func (c *motdCache) next() *source.Quote {
+	if len(c.Quotes) == 0 {
+		return nil
+	}
+	q := c.Quotes[c.Next%len(c.Quotes)]
+	c.Next = (c.Next + 1) % len(c.Quotes)
+	return q
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,168 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	MARK_NONE    = "[ ]"
+	MARK_INCLUDE = "[+]"
+	MARK_EXCLUDE = "[-]"
+)
+
+// multiSelect lets items be toggled between left alone, included and
+// excluded, include and exclude give the starting marks
+func multiSelect(label string, items, include, exclude []string) ([]string, []string, error) {
+	marks := map[string]string{}
+	for _, i := range include {
+		marks[strings.ToLower(i)] = MARK_INCLUDE
+	}
+	for _, i := range exclude {
+		marks[strings.ToLower(i)] = MARK_EXCLUDE
+	}
+	mark := func(item string) string {
+		if m, ok := marks[strings.ToLower(item)]; ok {
+			return m
+		}
+		return MARK_NONE
+	}
+
+	cursor, scroll := 0, 0
+	for {
+		included, excluded := 0, 0
+		lines := make([]string, 0, len(items)+1)
+		for _, item := range items {
+			switch mark(item) {
+			case MARK_INCLUDE:
+				included++
+			case MARK_EXCLUDE:
+				excluded++
+			}
+			lines = append(lines, mark(item)+" "+item)
+		}
+		done := fmt.Sprintf("Done (%d in, %d out)", included, excluded)
+		prompt := promptui.Select{
+			Label: label + " — select to toggle " + MARK_INCLUDE + " any of / " + MARK_EXCLUDE + " none of",
+			Items: append([]string{done}, lines...),
+			Size:  10,
+		}
+		i, _, err := prompt.RunCursorAt(cursor, scroll)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 {
+			break
+		}
+		cursor, scroll = i, prompt.ScrollPosition()
+
+		key := strings.ToLower(items[i-1])
+		switch marks[key] {
+		case MARK_INCLUDE:
+			marks[key] = MARK_EXCLUDE
+		case MARK_EXCLUDE:
+			delete(marks, key)
+		default:
+			marks[key] = MARK_INCLUDE
+		}
+	}
+
+	// kept in the order of the items, marks on items filtered out stay
+	var in, out []string
+	shown := map[string]bool{}
+	for _, item := range items {
+		shown[strings.ToLower(item)] = true
+	}
+	for _, item := range append(append([]string{}, include...), exclude...) {
+		if !shown[strings.ToLower(item)] {
+			items = append(items, item)
+			shown[strings.ToLower(item)] = true
+		}
+	}
+	for _, item := range items {
+		switch mark(item) {
+		case MARK_INCLUDE:
+			in = append(in, item)
+		case MARK_EXCLUDE:
+			out = append(out, item)
+		}
+	}
+	return in, out, nil
+}
+
+// selectMany changes the genres or authors of qo and goes further
+func (t *Term) selectMany(ctx context.Context, qo *source.QueryOptions) {
+	items := []string{"Genres", "Authors", GO_BACK}
+	prompt := promptui.Select{
+		Label: "Choose",
+		Items: items,
+	}
+	_, result, err := prompt.Run()
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return
+	}
+
+	qoTemp := &source.QueryOptions{}
+	if qo != nil {
+		*qoTemp = *qo
+		qoTemp.Page, qoTemp.Limit = 0, 0
+	}
+	switch result {
+	case items[0]:
+		if !t.chooseGenres(ctx, qoTemp) {
+			return
+		}
+	case items[1]:
+		if !t.chooseAuthors(ctx, qoTemp) {
+			return
+		}
+	case GO_BACK:
+		return
+	}
+	t.goFurther(ctx, qoTemp)
+}
+
+// chooseGenres sets the genres of qo, false when nothing could be chosen
+func (t *Term) chooseGenres(ctx context.Context, qo *source.QueryOptions) bool {
+	items, err := t.source.AllGenres(ctx)
+	if err != nil {
+		t.Error <- fmt.Errorf("could not get categories from source: %v", err)
+		return false
+	}
+	pterm.Info.Printfln("Number of items: %+v", len(items))
+	items = t.selectFilter(items)
+
+	in, out, err := multiSelect("Genres", items, qo.SelectedGenres(), qo.ExcludeGenres)
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return false
+	}
+	qo.Genre, qo.Genres, qo.ExcludeGenres = "", in, out
+	return true
+}
+
+// chooseAuthors sets the authors of qo, false when nothing could be chosen
+func (t *Term) chooseAuthors(ctx context.Context, qo *source.QueryOptions) bool {
+	items, err := t.source.AllAuthors(ctx)
+	if err != nil {
+		t.Error <- fmt.Errorf("could not get authors from source: %v", err)
+		return false
+	}
+	pterm.Info.Printfln("Number of items: %+v", len(items))
+	items = t.selectFilter(items)
+
+	in, out, err := multiSelect("Authors", items, qo.SelectedAuthors(), qo.ExcludeAuthors)
+	if err != nil {
+		t.Error <- fmt.Errorf("prompt failed: %v", err)
+		return false
+	}
+	qo.Author, qo.Authors, qo.ExcludeAuthors = "", in, out
+	return true
+}
@@ -0,0 +1,102 @@
+package interfaces
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/manifoldco/promptui"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+// pager tracks the position while paging through results
+type pager struct {
+	page       int
+	limit      int
+	totalPages int
+}
+
+// next and prev wrap around at both ends
+func (p *pager) next() {
+	p.page++
+	if p.page > p.totalPages {
+		p.page = 1
+	}
+}
+
+func (p *pager) prev() {
+	p.page--
+	if p.page < 1 {
+		p.page = p.lastPage()
+	}
+}
+
+func (p *pager) first() {
+	p.page = 1
+}
+
+func (p *pager) last() {
+	p.page = p.lastPage()
+}
+
+// jump goes to page, clamped to the existing pages
+func (p *pager) jump(page int) {
+	switch {
+	case page < 1:
+		page = 1
+	case page > p.lastPage():
+		page = p.lastPage()
+	}
+	p.page = page
+}
+
+// resize changes the page size and keeps the first quote shown on screen
+func (p *pager) resize(limit int) {
+	first := (p.page - 1) * p.limit
+	p.limit = limit
+	p.page = first/limit + 1
+	// the page count is unknown until the next page is loaded
+	p.totalPages = 0
+}
+
+// lastPage treats an empty result as one empty page
+func (p *pager) lastPage() int {
+	if p.totalPages < 1 {
+		return 1
+	}
+	return p.totalPages
+}
+
+func (p *pager) title(pag *source.Pagination) string {
+	title := fmt.Sprintf("PAGE %d/%d", p.page, p.lastPage())
+	if pag.TotalQuotes > 0 {
+		title += fmt.Sprintf(" · %d QUOTES", pag.TotalQuotes)
+	}
+	if pag.Truncated {
+		// the source stopped reading, more quotes may match
+		title += " · PARTIAL"
+	}
+	return title
+}
+
+// promptNumber asks for a number between min and max
+func promptNumber(label string, min, max int) (int, error) {
+	prompt := promptui.Prompt{
+		Label: label,
+		Validate: func(input string) error {
+			n, err := strconv.Atoi(input)
+			if err != nil {
+				return fmt.Errorf("invalid number")
+			}
+			if n < min || n > max {
+				return fmt.Errorf("must be between %d and %d", min, max)
+			}
+			return nil
+		},
+	}
+	result, err := prompt.Run()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(result)
+}
@@ -0,0 +1,65 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+const GOQU_HOME_ENV = "GOQU_HOME"
+
+// dataPath returns a path inside the goqu data directory.
+// The directory defaults to the user config dir and can be moved with $GOQU_HOME.
+func dataPath(elem ...string) (string, error) {
+	home := os.Getenv(GOQU_HOME_ENV)
+	if home == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		home = filepath.Join(dir, "goqu")
+	}
+	return filepath.Join(append([]string{home}, elem...)...), nil
+}
+
+// readJSON loads a data file, a missing file leaves v untouched
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON atomically replaces a data file
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// a temporary file of its own, concurrent goqu runs may race for path
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
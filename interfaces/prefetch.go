@@ -0,0 +1,186 @@
+package interfaces
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	// pages loaded ahead on each side of the current one
+	PREFETCH_DISTANCE = 2
+	// pages kept in memory while paging
+	PREFETCH_CACHE_SIZE = 16
+)
+
+type pageKey struct {
+	page  int32
+	limit int32
+}
+
+type page struct {
+	quotes []*source.Quote
+	pag    *source.Pagination
+	err    error
+}
+
+// pageFetch is a request in flight, done is closed once res is set
+type pageFetch struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	res    page
+}
+
+// pageLRU keeps the most recently used pages
+type pageLRU struct {
+	max   int
+	order *list.List
+	items map[pageKey]*list.Element
+}
+
+type lruEntry struct {
+	key  pageKey
+	page page
+}
+
+func newPageLRU(max int) *pageLRU {
+	return &pageLRU{max: max, order: list.New(), items: map[pageKey]*list.Element{}}
+}
+
+func (c *pageLRU) get(key pageKey) (page, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return page{}, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).page, true
+}
+
+func (c *pageLRU) add(key pageKey, p page) {
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry).page = p
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, page: p})
+	if c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// prefetcher loads the pages around the one shown in the background
+type prefetcher struct {
+	ctx context.Context
+	src source.Sources
+	qo  source.QueryOptions
+
+	mu       sync.Mutex
+	cache    *pageLRU
+	inflight map[pageKey]*pageFetch
+}
+
+func newPrefetcher(ctx context.Context, src source.Sources, qo *source.QueryOptions) *prefetcher {
+	return &prefetcher{
+		ctx:      ctx,
+		src:      src,
+		qo:       *qo,
+		cache:    newPageLRU(PREFETCH_CACHE_SIZE),
+		inflight: map[pageKey]*pageFetch{},
+	}
+}
+
+// get returns a page, waiting for its prefetch or loading it right away
+func (p *prefetcher) get(number, limit int32) ([]*source.Quote, *source.Pagination, error) {
+	key := pageKey{number, limit}
+	p.mu.Lock()
+	if cached, ok := p.cache.get(key); ok {
+		p.mu.Unlock()
+		return cached.quotes, cached.pag, nil
+	}
+	fetch, ok := p.inflight[key]
+	p.mu.Unlock()
+
+	if ok {
+		select {
+		case <-fetch.done:
+			if fetch.res.err == nil {
+				return fetch.res.quotes, fetch.res.pag, nil
+			}
+			// a failed or cancelled prefetch is retried in the foreground
+		case <-p.ctx.Done():
+			return nil, nil, p.ctx.Err()
+		}
+	}
+
+	res := p.load(p.ctx, key)
+	return res.quotes, res.pag, res.err
+}
+
+// around prefetches the neighbours of the current page and
+// cancels the requests that moved out of reach
+func (p *prefetcher) around(number, limit int32, totalPages int) {
+	wanted := map[pageKey]bool{}
+	for d := int32(-PREFETCH_DISTANCE); d <= PREFETCH_DISTANCE; d++ {
+		n := number + d
+		if d != 0 && n >= 1 && int(n) <= totalPages {
+			wanted[pageKey{n, limit}] = true
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, fetch := range p.inflight {
+		if !wanted[key] {
+			fetch.cancel()
+			delete(p.inflight, key)
+		}
+	}
+	for key := range wanted {
+		if _, ok := p.cache.get(key); ok {
+			continue
+		}
+		if _, ok := p.inflight[key]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(source.Quietly(p.ctx))
+		fetch := &pageFetch{cancel: cancel, done: make(chan struct{})}
+		p.inflight[key] = fetch
+		go func(key pageKey) {
+			fetch.res = p.load(ctx, key)
+			close(fetch.done)
+			cancel()
+			p.mu.Lock()
+			if p.inflight[key] == fetch {
+				delete(p.inflight, key)
+			}
+			p.mu.Unlock()
+		}(key)
+	}
+}
+
+// stop cancels every request still in flight
+func (p *prefetcher) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, fetch := range p.inflight {
+		fetch.cancel()
+		delete(p.inflight, key)
+	}
+}
+
+func (p *prefetcher) load(ctx context.Context, key pageKey) page {
+	opt := p.qo
+	opt.Page, opt.Limit = key.page, key.limit
+	quotes, pag, err := p.src.Quotes(ctx, &opt)
+	res := page{quotes: quotes, pag: pag, err: err}
+	if err == nil && pag != nil {
+		p.mu.Lock()
+		p.cache.add(key, res)
+		p.mu.Unlock()
+	}
+	return res
+}
@@ -0,0 +1,100 @@
+package interfaces
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+// queryFlags are the flags selecting quotes, shared by the commands
+type queryFlags struct {
+	authors    stringList
+	genres     stringList
+	notAuthors stringList
+	notGenres  stringList
+	query      *string
+	filter     *string
+	sort       *string
+	seed       *int64
+
+	minChars *int
+	maxChars *int
+	minWords *int
+	maxWords *int
+	maxLines *int
+	fits     *int
+}
+
+func addQueryFlags(fs *flag.FlagSet) *queryFlags {
+	f := &queryFlags{
+		query:  fs.String("query", "", "only quotes containing this term"),
+		filter: fs.String("filter", "", "only quotes matching annotations, e.g. 'rating>=4 tag:onboarding note:team'"),
+		sort:   fs.String("sort", "", "order by "+strings.Join(source.SortKeys, ", ")),
+		seed:   fs.Int64("seed", 0, "seed of the random sort, 0 for a new order every run"),
+
+		minChars: fs.Int("min-chars", 0, "only quotes of at least this many characters"),
+		maxChars: fs.Int("max-chars", 0, "only quotes of at most this many characters"),
+		minWords: fs.Int("min-words", 0, "only quotes of at least this many words"),
+		maxWords: fs.Int("max-words", 0, "only quotes of at most this many words"),
+		maxLines: fs.Int("max-lines", 0, "only quotes taking at most this many lines on screen"),
+		fits:     fs.Int("fits", 0, "only quotes fitting with their author in this many characters, e.g. 280"),
+	}
+	fs.Var(&f.authors, "author", "only quotes by this author, repeat for any of several")
+	fs.Var(&f.genres, "genre", "only quotes of this genre, repeat for any of several")
+	fs.Var(&f.notAuthors, "not-author", "leave out quotes by this author, repeatable")
+	fs.Var(&f.notGenres, "not-genre", "leave out quotes of this genre, repeatable")
+	return f
+}
+
+func (f *queryFlags) options() (*source.QueryOptions, error) {
+	if err := source.ValidSort(*f.sort); err != nil {
+		return nil, err
+	}
+	qo := &source.QueryOptions{
+		Authors: f.authors,
+		Genres:  f.genres,
+		Query:   *f.query,
+		Sort:    *f.sort,
+		Seed:    *f.seed,
+
+		MinChars: *f.minChars,
+		MaxChars: *f.maxChars,
+		MinWords: *f.minWords,
+		MaxWords: *f.maxWords,
+		MaxLines: *f.maxLines,
+		FitsIn:   *f.fits,
+
+		ExcludeAuthors: f.notAuthors,
+		ExcludeGenres:  f.notGenres,
+	}
+	if (qo.MaxChars > 0 && qo.MinChars > qo.MaxChars) || (qo.MaxWords > 0 && qo.MinWords > qo.MaxWords) {
+		return nil, fmt.Errorf("minimum length is above the maximum")
+	}
+	if err := source.ParseFilter(*f.filter, qo); err != nil {
+		return nil, err
+	}
+	reseed(qo)
+	return qo, nil
+}
+
+// reseed gives a random sort without a seed a new order
+func reseed(qo *source.QueryOptions) {
+	if qo.Sort == source.SORT_RANDOM && qo.Seed == 0 {
+		qo.Seed, qo.SeedPicked = time.Now().UnixNano(), true
+	}
+}
+
+// queryable adds the multi-selection, length constraints and sorting that
+// src cannot apply by itself
+func queryable(src source.Sources) source.Sources {
+	notes, err := loadAnnotations()
+	if err != nil {
+		pterm.Warning.Printfln("annotations not shown: %v", err)
+	}
+	return source.NewSorted(source.NewFiltered(source.NewAnnotated(source.NewMerged(src), notes)))
+}
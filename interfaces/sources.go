@@ -0,0 +1,163 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/custompointofview/goqu/assets"
+	"github.com/custompointofview/goqu/source"
+)
+
+const SOURCE_USAGE = "quote source: quotegarden, mirror, library, assets, fortune:<path>, collection:<name> or a registered external source"
+
+var builtinSources = []string{"quotegarden", "mirror", "library", "assets"}
+
+// openSource resolves a source name as given on the command line,
+// remote sources come with failover
+func openSource(name string) (source.Sources, error) {
+	src, err := openBareSource(name)
+	if err != nil {
+		return nil, err
+	}
+	if !isRemoteSource(name) {
+		return src, nil
+	}
+	if name == "" {
+		name = "quotegarden"
+	}
+	return withFailover(name, src)
+}
+
+func openBareSource(name string) (source.Sources, error) {
+	switch {
+	case name == "" || name == "quotegarden":
+		qg, err := newQuoteGarden()
+		if err != nil {
+			return nil, err
+		}
+		return qg, nil
+	case name == "mirror":
+		path, err := dataPath("mirror", source.MIRROR_DB)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("no local mirror yet, run `goqu sync` first")
+		}
+		return source.OpenLocalDB(path)
+	case name == "library":
+		path, err := dataPath(LIBRARY_DB)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		return source.OpenLocalDB(path)
+	case name == "assets":
+		return source.NewStatic(assetQuotes()), nil
+	case strings.HasPrefix(name, "collection:"):
+		return openCollection(strings.TrimPrefix(name, "collection:"))
+	case strings.HasPrefix(name, "fortune:"):
+		return source.OpenFortune(strings.Split(strings.TrimPrefix(name, "fortune:"), string(os.PathListSeparator))...)
+	}
+	src, ok, err := openExternal(name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown source: %s", name)
+	}
+	return src, nil
+}
+
+// isRemoteSource tells the sources that can fail at any time
+func isRemoteSource(name string) bool {
+	return name == "" || name == "quotegarden" || !isBuiltinSource(name)
+}
+
+func isBuiltinSource(name string) bool {
+	for _, b := range builtinSources {
+		if b == name {
+			return true
+		}
+	}
+	return strings.Contains(name, ":")
+}
+
+// assetQuotes turns the bundled corpus into quotes
+func assetQuotes() []*source.Quote {
+	quotes := make([]*source.Quote, 0, len(assets.QUOTES_GOKU))
+	for i, text := range assets.QUOTES_GOKU {
+		quotes = append(quotes, &source.Quote{
+			ID:       fmt.Sprintf("goku-%d", i),
+			Text:     text,
+			Author:   "Goku",
+			Work:     "Dragon Ball Z",
+			Genre:    "anime",
+			Language: "en",
+			Provider: "GoQu",
+		})
+	}
+	return quotes
+}
+
+// silence turns off interactive feedback of sources used in the background
+func silence(src source.Sources) {
+	switch s := src.(type) {
+	case *source.QuoteGarden:
+		s.Silent = true
+	case *source.Failover:
+		s.Notify = nil
+		silence(s.Primary())
+	}
+}
+
+// quotePicker draws random quotes matching a query from any source
+type quotePicker struct {
+	src        source.Sources
+	qo         source.QueryOptions
+	totalPages int
+	rand       *rand.Rand
+}
+
+func newQuotePicker(src source.Sources, qo *source.QueryOptions) *quotePicker {
+	return &quotePicker{
+		src:  source.NewFiltered(source.NewMerged(src)),
+		qo:   *qo,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (p *quotePicker) next(ctx context.Context) (*source.Quote, error) {
+	if !p.qo.Selects() {
+		return p.src.RandomQuote(ctx)
+	}
+	opt := p.qo
+	opt.Page = 1
+	if p.totalPages > 1 {
+		opt.Page = int32(p.rand.Intn(p.totalPages) + 1)
+	}
+	quotes, pag, err := p.src.Quotes(ctx, &opt)
+	if err == nil && len(quotes) == 0 && opt.Page != 1 {
+		// a page announced while the source was still being read may
+		// turn out empty, the first page has quotes if any match
+		opt.Page = 1
+		quotes, pag, err = p.src.Quotes(ctx, &opt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pag != nil {
+		p.totalPages = pag.TotalPages
+	}
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("no quote matches the selection")
+	}
+	return quotes[p.rand.Intn(len(quotes))], nil
+}
@@ -0,0 +1,105 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+func init() {
+	commands = append(commands, command{
+		name:  "sync",
+		usage: "mirror the whole QuoteGarden corpus locally",
+		run:   runSync,
+	})
+}
+
+func runSync(ctx context.Context, args []string) error {
+	defaultDir, err := dataPath("mirror")
+	if err != nil {
+		return err
+	}
+
+	fs := newFlagSet("sync")
+	dir := fs.String("dir", defaultDir, "directory of the local mirror")
+	pageSize := fs.Int("page-size", source.DEFAULT_MIRROR_PAGE_SIZE, "quotes requested per page")
+	concurrency := fs.Int("concurrency", source.DEFAULT_MIRROR_CONCURRENCY, "maximum parallel requests")
+	interval := fs.Duration("interval", source.DEFAULT_MIRROR_INTERVAL, "minimum delay between requests")
+	verbose := fs.Bool("verbose", false, "list the IDs of every changed quote")
+	strict := fs.Bool("strict", false, "fail on response fields goqu does not know, to catch API changes")
+	allowShrink := fs.Bool("allow-shrink", false, "apply a run that removes most of the mirror")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	qg, err := newQuoteGarden()
+	if err != nil {
+		return err
+	}
+	qg.Silent = true
+	qg.Strict = *strict
+
+	pterm.DefaultSection.Println("Syncing QuoteGarden into", *dir)
+	var bar *pterm.ProgressbarPrinter
+	var queued int32
+	ctx = source.WithQueuedFeedback(ctx, func(time.Duration) {
+		atomic.StoreInt32(&queued, 1)
+	})
+	mirror := source.NewMirror(qg, source.MirrorOptions{
+		Dir:         *dir,
+		PageSize:    *pageSize,
+		Concurrency: *concurrency,
+		Interval:    *interval,
+		AllowShrink: *allowShrink,
+		Progress: func(done, total int) {
+			if bar == nil {
+				bar, _ = pterm.DefaultProgressbar.WithTitle("Pages").WithTotal(total).WithCurrent(done).Start()
+				return
+			}
+			bar.Total = total
+			bar.Title = "Pages"
+			if atomic.SwapInt32(&queued, 0) == 1 {
+				bar.Title = "Pages (rate limited)"
+			}
+			bar.Add(done - bar.Current)
+		},
+	})
+
+	diff, err := mirror.Run(ctx)
+	if bar != nil {
+		bar.Stop()
+	}
+	if err != nil {
+		return fmt.Errorf("sync interrupted, run again to resume: %v", err)
+	}
+
+	pterm.Success.Printfln("%d quotes mirrored: %d added, %d removed, %d changed",
+		diff.Total, len(diff.Added), len(diff.Removed), len(diff.Changed))
+	if host, err := url.Parse(qg.BaseURL); err == nil && rateLimiter != nil && rateLimiter.Budget() > 0 {
+		pterm.Info.Printfln("%d of %d requests to %s today", rateLimiter.Used(host.Host), rateLimiter.Budget(), host.Host)
+	}
+	if *verbose {
+		printIDs("Added", diff.Added)
+		printIDs("Removed", diff.Removed)
+		printIDs("Changed", diff.Changed)
+	}
+	return nil
+}
+
+func printIDs(title string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	items := make([]pterm.BulletListItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, pterm.BulletListItem{Text: id})
+	}
+	pterm.DefaultSection.WithLevel(2).Println(title)
+	pterm.DefaultBulletList.WithItems(items).Render()
+}
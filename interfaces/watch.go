@@ -0,0 +1,177 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pterm/pterm"
+
+	"github.com/custompointofview/goqu/source"
+)
+
+const (
+	DEFAULT_WATCH_INTERVAL = 5 * time.Minute
+	// how often the terminal size is checked
+	watchResizePoll = time.Second
+)
+
+func init() {
+	commands = append(commands, command{
+		name:  "watch",
+		usage: "keep showing a new quote every interval, for dashboards and tmux",
+		run:   runWatch,
+	})
+}
+
+type watchResult struct {
+	quote *source.Quote
+	err   error
+}
+
+func runWatch(ctx context.Context, args []string) error {
+	fs := newFlagSet("watch")
+	srcName := fs.String("source", "quotegarden", SOURCE_USAGE)
+	interval := fs.Duration("interval", DEFAULT_WATCH_INTERVAL, "time between quotes")
+	oneline := fs.Bool("oneline", false, "compact single line output for status bars")
+	once := fs.Bool("once", false, "print a single quote and exit, e.g. for tmux #(goqu watch -oneline -once)")
+	width := fs.Int("width", 0, "maximum width of the one line output, 0 for the terminal width")
+	author := fs.String("author", "", "only quotes by this author")
+	genre := fs.String("genre", "", "only quotes of this genre")
+	query := fs.String("query", "", "only quotes containing this term")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	src, err := openSource(*srcName)
+	if err != nil {
+		return err
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+	silence(src)
+	if failover, ok := src.(*source.Failover); ok {
+		failover.ProbeEvery(ctx, FAILOVER_PROBE_INTERVAL)
+	}
+	picker := newQuotePicker(src, &source.QueryOptions{Author: *author, Genre: *genre, Query: *query})
+
+	if *once {
+		q, err := picker.next(ctx)
+		if err != nil {
+			return err
+		}
+		if *oneline {
+			fmt.Println(onelineQuote(q, *width))
+		} else {
+			q.Print()
+		}
+		return nil
+	}
+
+	// the next quote is fetched while the current one is shown
+	prefetch := func() <-chan watchResult {
+		ch := make(chan watchResult, 1)
+		go func() {
+			q, err := picker.next(ctx)
+			ch <- watchResult{q, err}
+		}()
+		return ch
+	}
+
+	var render func(q *source.Quote, status string)
+	if *oneline {
+		render = func(q *source.Quote, status string) {
+			if q != nil {
+				fmt.Printf("\r\033[K%s", onelineQuote(q, *width))
+			}
+		}
+		defer fmt.Println()
+	} else {
+		area, err := pterm.DefaultArea.Start()
+		if err != nil {
+			return err
+		}
+		defer area.Stop()
+		render = func(q *source.Quote, status string) {
+			content := ""
+			if q != nil {
+				content = q.HSprint()
+			}
+			area.Update(content + pterm.FgGray.Sprint(status))
+		}
+	}
+
+	var current *source.Quote
+	var status string
+	next := prefetch()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	resize := time.NewTicker(watchResizePoll)
+	defer resize.Stop()
+	termWidth, termHeight := pterm.GetTerminalWidth(), pterm.GetTerminalHeight()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			res := <-next
+			if res.err != nil {
+				status = fmt.Sprintf("could not get a quote: %v, retrying at %s",
+					res.err, time.Now().Add(*interval).Format("15:04:05"))
+			} else {
+				current = res.quote
+				status = fmt.Sprintf("next quote at %s", time.Now().Add(*interval).Format("15:04:05"))
+			}
+			render(current, status)
+			next = prefetch()
+			timer.Reset(*interval)
+		case <-resize.C:
+			w, h := pterm.GetTerminalWidth(), pterm.GetTerminalHeight()
+			if w == termWidth && h == termHeight {
+				continue
+			}
+			termWidth, termHeight = w, h
+			if !*oneline {
+				// the old frame no longer matches the line wrapping
+				fmt.Print("\033[H\033[2J")
+			}
+			render(current, status)
+		}
+	}
+}
+
+// onelineQuote fits a quote on a single line of at most width runes
+func onelineQuote(q *source.Quote, width int) string {
+	if width <= 0 {
+		width = pterm.GetTerminalWidth()
+	}
+	text := strings.Join(strings.Fields(q.Text), " ")
+	suffix := ""
+	if attribution := q.Attribution(); attribution != "" {
+		suffix = " — " + attribution
+	}
+	if utf8.RuneCountInString(text+suffix) <= width {
+		return text + suffix
+	}
+	room := width - utf8.RuneCountInString(suffix) - 1
+	if room < width/2 {
+		// long attributions give way to the quote
+		suffix = ""
+		room = width - 1
+		if utf8.RuneCountInString(text) <= width {
+			return text
+		}
+	}
+	if room <= 0 {
+		return ""
+	}
+	return string([]rune(text)[:room]) + "…" + suffix
+}
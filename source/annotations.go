@@ -0,0 +1,375 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	MIN_RATING = 1
+	MAX_RATING = 5
+)
+
+// Annotation holds the personal rating, note and tags of a quote
+type Annotation struct {
+	Rating int      `json:"rating,omitempty"`
+	Note   string   `json:"note,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	// the quote is kept for reference, its source may be gone
+	Text      string    `json:"text,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IsEmpty tells whether the annotation holds nothing of the user
+func (a *Annotation) IsEmpty() bool {
+	return a.Rating == 0 && strings.TrimSpace(a.Note) == "" && len(a.Tags) == 0
+}
+
+// HasTag checks the personal tags, ignoring case
+func (a *Annotation) HasTag(tag string) bool {
+	return containsFold(a.Tags, tag)
+}
+
+// Sprint formats the rating and tags on one line, followed by the note
+func (a *Annotation) Sprint() string {
+	var parts []string
+	if a.Rating > 0 {
+		parts = append(parts, strings.Repeat("★", a.Rating)+strings.Repeat("☆", MAX_RATING-a.Rating))
+	}
+	for _, t := range a.Tags {
+		parts = append(parts, "#"+t)
+	}
+	s := pterm.FgYellow.Sprint(strings.Join(parts, " "))
+	if note := strings.TrimSpace(a.Note); note != "" {
+		if len(parts) > 0 {
+			s += "\n"
+		}
+		s += pterm.FgGray.Sprint(strings.TrimSpace(pterm.DefaultParagraph.WithMaxWidth(LINE_WIDTH).Sprint(note)))
+	}
+	return s
+}
+
+// Annotations stores the annotations of quotes by Quote.ID in a file
+type Annotations struct {
+	path string
+
+	mu     sync.Mutex
+	Quotes map[string]*Annotation `json:"quotes"`
+}
+
+// OpenAnnotations loads the annotations file, a missing file starts empty
+func OpenAnnotations(path string) (*Annotations, error) {
+	a := &Annotations{path: path, Quotes: map[string]*Annotation{}}
+	if err := readJSONFile(path, a); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	if a.Quotes == nil {
+		a.Quotes = map[string]*Annotation{}
+	}
+	return a, nil
+}
+
+// Get returns a copy of the annotation of a quote, nil when there is none
+func (a *Annotations) Get(id string) *Annotation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if an, ok := a.Quotes[id]; ok {
+		c := *an
+		c.Tags = append([]string(nil), an.Tags...)
+		return &c
+	}
+	return nil
+}
+
+// Set stores the annotation of q, an empty annotation removes it
+func (a *Annotations) Set(q *Quote, an *Annotation) error {
+	if q.ID == "" {
+		return fmt.Errorf("quote has no id and cannot be annotated")
+	}
+	if an.Rating != 0 && (an.Rating < MIN_RATING || an.Rating > MAX_RATING) {
+		return fmt.Errorf("rating must be between %d and %d", MIN_RATING, MAX_RATING)
+	}
+	c := *an
+	c.Tags = uniqueFold(an.Tags)
+	c.Note = strings.TrimSpace(an.Note)
+	c.UpdatedAt = time.Now()
+
+	return a.Update(func(quotes map[string]*Annotation) error {
+		// a quote known by its id only keeps the text stored before
+		c.Text, c.Author = q.Text, q.Author
+		if old, ok := quotes[q.ID]; ok && q.Text == "" {
+			c.Text, c.Author = old.Text, old.Author
+		}
+		if c.IsEmpty() {
+			delete(quotes, q.ID)
+			q.Annotation = nil
+		} else {
+			quotes[q.ID] = &c
+			q.Annotation = &c
+		}
+		return nil
+	})
+}
+
+// Update applies change to the annotations as the file holds them now,
+// locked against the other goqu processes so that their changes are kept
+func (a *Annotations) Update(change func(quotes map[string]*Annotation) error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	unlock, err := LockFile(a.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	stored, err := OpenAnnotations(a.path)
+	if err != nil {
+		return err
+	}
+	a.Quotes = stored.Quotes
+	if err := change(a.Quotes); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return err
+	}
+	return writeJSONFile(a.path, a)
+}
+
+// IDs returns the ids of the annotated quotes, sorted
+func (a *Annotations) IDs() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ids := make([]string, 0, len(a.Quotes))
+	for id := range a.Quotes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// All returns a copy of every annotation by quote id
+func (a *Annotations) All() map[string]*Annotation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	all := make(map[string]*Annotation, len(a.Quotes))
+	for id, an := range a.Quotes {
+		c := *an
+		c.Tags = append([]string(nil), an.Tags...)
+		all[id] = &c
+	}
+	return all
+}
+
+// Replace stores the annotations as they are, keeping their dates,
+// e.g. when they come from another machine
+func (a *Annotations) Replace(all map[string]*Annotation) error {
+	return a.Update(func(quotes map[string]*Annotation) error {
+		for id := range quotes {
+			delete(quotes, id)
+		}
+		for id, an := range all {
+			if !an.IsEmpty() {
+				c := *an
+				quotes[id] = &c
+			}
+		}
+		return nil
+	})
+}
+
+// Tags returns every personal tag in use, sorted
+func (a *Annotations) Tags() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var tags []string
+	for _, an := range a.Quotes {
+		for _, t := range an.Tags {
+			if !containsFold(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Annotate attaches the stored annotations to the quotes
+func (a *Annotations) Annotate(quotes ...*Quote) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, q := range quotes {
+		if q != nil && q.ID != "" {
+			q.Annotation = a.Quotes[q.ID]
+		}
+	}
+}
+
+// HasAnnotationFilter tells whether the options filter on annotations
+func (qo *QueryOptions) HasAnnotationFilter() bool {
+	return qo.MinRating > 0 || qo.MaxRating > 0 || len(qo.Tagged) > 0 || qo.NoteQuery != ""
+}
+
+// withoutAnnotationFilter returns the options a source can be given
+func (qo *QueryOptions) withoutAnnotationFilter() QueryOptions {
+	opt := *qo
+	opt.MinRating, opt.MaxRating, opt.Tagged, opt.NoteQuery = 0, 0, nil, ""
+	return opt
+}
+
+func matchesAnnotation(q *Quote, qo *QueryOptions) bool {
+	if !qo.HasAnnotationFilter() {
+		return true
+	}
+	a := q.Annotation
+	if a == nil {
+		return false
+	}
+	if (qo.MinRating > 0 && a.Rating < qo.MinRating) || (qo.MaxRating > 0 && (a.Rating == 0 || a.Rating > qo.MaxRating)) {
+		return false
+	}
+	for _, t := range qo.Tagged {
+		if !a.HasTag(t) {
+			return false
+		}
+	}
+	return qo.NoteQuery == "" || strings.Contains(strings.ToLower(a.Note), strings.ToLower(qo.NoteQuery))
+}
+
+// ParseFilter reads a filter expression into qo, e.g. `rating>=4 tag:onboarding`.
+// It knows rating with = < <= > >=, tag: and note:, other words search the
+// text along with the Query already set.
+func ParseFilter(expr string, qo *QueryOptions) error {
+	var words []string
+	for _, token := range strings.Fields(expr) {
+		lower := strings.ToLower(token)
+		switch {
+		case strings.HasPrefix(lower, "rating") && len(lower) > len("rating") && strings.ContainsRune("<=>", rune(lower[len("rating")])):
+			if err := parseRating(lower[len("rating"):], qo); err != nil {
+				return fmt.Errorf("invalid %q: %v", token, err)
+			}
+		case strings.HasPrefix(lower, "tag:") && len(token) > len("tag:"):
+			qo.Tagged = uniqueFold(append(qo.Tagged, token[len("tag:"):]))
+		case strings.HasPrefix(lower, "note:") && len(token) > len("note:"):
+			qo.NoteQuery = token[len("note:"):]
+		default:
+			words = append(words, token)
+		}
+	}
+	if len(words) > 0 {
+		qo.Query = strings.TrimSpace(qo.Query + " " + strings.Join(words, " "))
+	}
+	return nil
+}
+
+func parseRating(cond string, qo *QueryOptions) error {
+	var op string
+	for _, o := range []string{">=", "<=", "=", ">", "<"} {
+		if strings.HasPrefix(cond, o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		return fmt.Errorf("expected rating>=N, rating<=N, rating=N, rating>N or rating<N")
+	}
+	n, err := strconv.Atoi(cond[len(op):])
+	if err != nil {
+		return fmt.Errorf("not a number")
+	}
+	min, max := qo.MinRating, qo.MaxRating
+	switch op {
+	case ">=":
+		min = n
+	case ">":
+		min = n + 1
+	case "<=":
+		max = n
+	case "<":
+		max = n - 1
+	case "=":
+		min, max = n, n
+	}
+	// unrated quotes never match, so the bounds stay within the ratings
+	lower, upper := op[0] == '>' || op == "=", op[0] == '<' || op == "="
+	if lower && min < MIN_RATING {
+		min = MIN_RATING
+	}
+	if upper && max > MAX_RATING {
+		max = MAX_RATING
+	}
+	if min > MAX_RATING || (upper && max < MIN_RATING) || (max > 0 && min > max) {
+		return fmt.Errorf("no rating between %d and %d matches", MIN_RATING, MAX_RATING)
+	}
+	qo.MinRating, qo.MaxRating = min, max
+	return nil
+}
+
+// annotatedAuthors returns the authors of the annotated quotes matching the
+// annotation filter of qo, known is false when one of them has no author
+func (a *Annotations) annotatedAuthors(qo *QueryOptions) (authors []string, known bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, an := range a.Quotes {
+		if !matchesAnnotation(&Quote{Annotation: an}, qo) {
+			continue
+		}
+		if strings.TrimSpace(an.Author) == "" {
+			return nil, false
+		}
+		authors = append(authors, an.Author)
+	}
+	sort.Strings(authors)
+	return uniqueFold(authors), true
+}
+
+// Annotated attaches the stored annotations to the quotes of a source
+type Annotated struct {
+	Sources
+	notes *Annotations
+}
+
+// NewAnnotated creates an Annotated object
+func NewAnnotated(src Sources, notes *Annotations) *Annotated {
+	return &Annotated{Sources: src, notes: notes}
+}
+
+func (a *Annotated) RandomQuote(ctx context.Context) (*Quote, error) {
+	q, err := a.Sources.RandomQuote(ctx)
+	if err == nil && a.notes != nil {
+		a.notes.Annotate(q)
+	}
+	return q, err
+}
+
+func (a *Annotated) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	quotes, pag, err := a.Sources.Quotes(ctx, options)
+	if err == nil && a.notes != nil {
+		a.notes.Annotate(quotes...)
+	}
+	return quotes, pag, err
+}
+
+// annotations gives Filtered the annotated quotes to start from
+func (a *Annotated) annotations() *Annotations {
+	return a.notes
+}
+
+func (a *Annotated) SortsBy(key string) bool {
+	sorting, ok := a.Sources.(Sorting)
+	return ok && sorting.SortsBy(key)
+}
+
+func (a *Annotated) AppliesConstraints() bool {
+	c, ok := a.Sources.(Constraining)
+	return ok && c.AppliesConstraints()
+}
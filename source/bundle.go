@@ -0,0 +1,219 @@
+package source
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	BUNDLE_FORMAT   = "goqu-bundle"
+	BUNDLE_VERSION  = 1
+	BUNDLE_MANIFEST = "manifest.json"
+	BUNDLE_EXT      = ".goqu"
+	// collections are stored under this directory of the bundle
+	BUNDLE_COLLECTIONS_DIR = "collections"
+	// no file of a bundle is read beyond this size
+	BUNDLE_MAX_FILE_SIZE = 64 << 20
+)
+
+// Manifest describes the content of a bundle
+type Manifest struct {
+	Format      string        `json:"format"`
+	Version     int           `json:"version"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	Collections []BundleEntry `json:"collections"`
+	// Signature is set by ReadBundle when the bundle carries a valid one
+	Signature *Signature `json:"-"`
+}
+
+// BundleEntry is a collection file of a bundle and its checksum
+type BundleEntry struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Quotes int    `json:"quotes"`
+	SHA256 string `json:"sha256"`
+}
+
+// WriteBundle writes the collections as a zip bundle with a manifest,
+// signed with key unless it is nil
+func WriteBundle(w io.Writer, collections []*Collection, key ed25519.PrivateKey) error {
+	manifest := &Manifest{Format: BUNDLE_FORMAT, Version: BUNDLE_VERSION, CreatedAt: time.Now().UTC()}
+	files := map[string][]byte{}
+	for _, c := range collections {
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return err
+		}
+		file := path.Join(BUNDLE_COLLECTIONS_DIR, CollectionFile(c.Name))
+		if _, ok := files[file]; ok {
+			return fmt.Errorf("two collections named like %q", c.Name)
+		}
+		files[file] = data
+		sum := sha256.Sum256(data)
+		manifest.Collections = append(manifest.Collections, BundleEntry{
+			Name:   c.Name,
+			File:   file,
+			Quotes: len(c.Items),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	write := func(name string, data []byte) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: manifest.CreatedAt})
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	}
+	// the manifest comes first so it can be read without the rest
+	if err := write(BUNDLE_MANIFEST, manifestData); err != nil {
+		return err
+	}
+	if key != nil {
+		// the manifest holds the checksums, signing it covers the collections
+		sigData, err := json.MarshalIndent(sign(key, manifestData), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := write(BUNDLE_SIGNATURE, sigData); err != nil {
+			return err
+		}
+	}
+	for _, e := range manifest.Collections {
+		if err := write(e.File, files[e.File]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteBundleFile writes a bundle to path
+func WriteBundleFile(path string, collections []*Collection, key ed25519.PrivateKey) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteBundle(f, collections, key); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadBundle checks the manifest, the signature when there is one and the
+// checksums of a bundle, and returns its collections. Whether the signer
+// is trusted is up to the caller.
+func ReadBundle(r io.ReaderAt, size int64) (*Manifest, []*Collection, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a bundle: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifestData, err := readZipFile(files[BUNDLE_MANIFEST])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %v", BUNDLE_MANIFEST, err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %v", BUNDLE_MANIFEST, err)
+	}
+	if manifest.Format != BUNDLE_FORMAT {
+		return nil, nil, fmt.Errorf("not a bundle: format %q", manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > BUNDLE_VERSION {
+		return nil, nil, fmt.Errorf("bundle version %d is not supported, this goqu reads up to version %d", manifest.Version, BUNDLE_VERSION)
+	}
+	damaged := "the bundle is damaged"
+	if f := files[BUNDLE_SIGNATURE]; f != nil {
+		sig := &Signature{}
+		if err := readZipJSON(f, sig); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %v", BUNDLE_SIGNATURE, err)
+		}
+		if err := sig.verify(manifestData); err != nil {
+			return nil, nil, fmt.Errorf("%v, the bundle was modified after it was signed", err)
+		}
+		manifest.Signature = sig
+		damaged = "the bundle was modified after it was signed"
+	}
+
+	var collections []*Collection
+	for _, e := range manifest.Collections {
+		data, err := readZipFile(files[e.File])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %v", e.File, err)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != e.SHA256 {
+			return nil, nil, fmt.Errorf("%s does not match its checksum, %s", e.File, damaged)
+		}
+		c := &Collection{}
+		if err := json.Unmarshal(data, c); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %v", e.File, err)
+		}
+		if c.Name != e.Name {
+			return nil, nil, fmt.Errorf("%s holds %q, the manifest says %q", e.File, c.Name, e.Name)
+		}
+		collections = append(collections, c)
+	}
+	return manifest, collections, nil
+}
+
+// ReadBundleFile reads the bundle at path
+func ReadBundleFile(path string) (*Manifest, []*Collection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	return ReadBundle(f, info.Size())
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f == nil {
+		return nil, fmt.Errorf("missing from the bundle")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	// the sizes in the zip directory are not to be trusted
+	data, err := io.ReadAll(io.LimitReader(rc, BUNDLE_MAX_FILE_SIZE+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > BUNDLE_MAX_FILE_SIZE {
+		return nil, fmt.Errorf("%s is larger than %d MB", f.Name, BUNDLE_MAX_FILE_SIZE>>20)
+	}
+	return data, nil
+}
+
+func readZipJSON(f *zip.File, v interface{}) error {
+	data, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,115 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const DEFAULT_CACHE_SIZE = 500
+
+// Cache keeps the latest quotes served by a source on disk,
+// so they can be served again while the source is down
+type Cache struct {
+	path string
+	max  int
+
+	mu     sync.Mutex
+	quotes []*Quote
+}
+
+// OpenCache loads the cache file, a missing file starts an empty cache
+func OpenCache(path string, max int) (*Cache, error) {
+	if max <= 0 {
+		max = DEFAULT_CACHE_SIZE
+	}
+	c := &Cache{path: path, max: max}
+	if err := readJSONFile(path, &c.quotes); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Add stores quotes as the most recent ones and drops the oldest beyond the size.
+// The file is only written when a quote is new or has changed.
+func (c *Cache) Add(quotes ...*Quote) error {
+	if len(quotes) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fresh := map[string]*Quote{}
+	for _, q := range quotes {
+		fresh[q.ID] = q
+	}
+	changed := len(fresh)
+	kept := make([]*Quote, 0, len(c.quotes)+len(quotes))
+	for _, q := range c.quotes {
+		if f, ok := fresh[q.ID]; !ok {
+			kept = append(kept, q)
+		} else if f.Equal(q) {
+			changed--
+		}
+	}
+	kept = append(kept, quotes...)
+	if len(kept) > c.max {
+		kept = kept[len(kept)-c.max:]
+	}
+	c.quotes = kept
+	// quotes served again only move up, the file gets the order on the
+	// next write
+	if changed == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return writeJSONFile(c.path, c.quotes)
+}
+
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.quotes)
+}
+
+func (c *Cache) static() *Static {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return NewStatic(append([]*Quote(nil), c.quotes...))
+}
+
+func (c *Cache) RandomQuote(ctx context.Context) (*Quote, error) {
+	return c.static().RandomQuote(ctx)
+}
+
+func (c *Cache) AllGenres(ctx context.Context) ([]string, error) {
+	return c.static().AllGenres(ctx)
+}
+
+func (c *Cache) AllAuthors(ctx context.Context) ([]string, error) {
+	return c.static().AllAuthors(ctx)
+}
+
+func (c *Cache) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	return c.static().Quotes(ctx, options)
+}
+
+func (c *Cache) SelectsMany() bool {
+	return true
+}
+
+func (c *Cache) AppliesConstraints() bool {
+	return true
+}
+
+func (c *Cache) SortsBy(key string) bool {
+	return ValidSort(key) == nil
+}
+
+func (c *Cache) PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	PrintQuotesPage(title, quotes, columns)
+}
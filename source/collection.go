@@ -0,0 +1,237 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// COLLECTION_EXT is the extension of the collection files in a Collections directory
+const COLLECTION_EXT = ".json"
+
+// Collection is a named, ordered set of quotes picked by the user.
+// It is a Sources listing its quotes in their curated order.
+type Collection struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Items       []*Quote  `json:"quotes"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// NewCollection creates an empty collection
+func NewCollection(name string) *Collection {
+	now := time.Now()
+	return &Collection{Name: name, Items: []*Quote{}, CreatedAt: now, UpdatedAt: now}
+}
+
+// collectionKey identifies a quote, by id or by its content when it has none
+func collectionKey(q *Quote) string {
+	if q.ID != "" {
+		return q.ID
+	}
+	return strings.ToLower(q.Author + "\x00" + strings.Join(strings.Fields(q.Text), " "))
+}
+
+// Index returns the position of a quote, -1 when it is not in the collection
+func (c *Collection) Index(q *Quote) int {
+	key := collectionKey(q)
+	for i, cq := range c.Items {
+		if collectionKey(cq) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add appends the quotes not in the collection yet and returns how many were added
+func (c *Collection) Add(quotes ...*Quote) int {
+	added := 0
+	for _, q := range quotes {
+		if c.Index(q) >= 0 {
+			continue
+		}
+		stored := *q
+		stored.ServedBy, stored.Annotation = "", nil
+		c.Items = append(c.Items, &stored)
+		added++
+	}
+	if added > 0 {
+		c.UpdatedAt = time.Now()
+	}
+	return added
+}
+
+// Remove drops the quote at position i, counted from 0
+func (c *Collection) Remove(i int) error {
+	if i < 0 || i >= len(c.Items) {
+		return fmt.Errorf("no quote %d in %q, it has %d", i+1, c.Name, len(c.Items))
+	}
+	c.Items = append(c.Items[:i], c.Items[i+1:]...)
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// Move puts the quote at position from to position to, counted from 0
+func (c *Collection) Move(from, to int) error {
+	if from < 0 || from >= len(c.Items) || to < 0 || to >= len(c.Items) {
+		return fmt.Errorf("positions must be between 1 and %d", len(c.Items))
+	}
+	q := c.Items[from]
+	c.Items = append(c.Items[:from], c.Items[from+1:]...)
+	c.Items = append(c.Items[:to], append([]*Quote{q}, c.Items[to:]...)...)
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+func (c *Collection) RandomQuote(ctx context.Context) (*Quote, error) {
+	return NewStatic(c.Items).RandomQuote(ctx)
+}
+
+func (c *Collection) AllGenres(ctx context.Context) ([]string, error) {
+	return NewStatic(c.Items).AllGenres(ctx)
+}
+
+func (c *Collection) AllAuthors(ctx context.Context) ([]string, error) {
+	return NewStatic(c.Items).AllAuthors(ctx)
+}
+
+func (c *Collection) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	return NewStatic(c.Items).Quotes(ctx, options)
+}
+
+func (c *Collection) SelectsMany() bool {
+	return true
+}
+
+func (c *Collection) AppliesConstraints() bool {
+	return true
+}
+
+func (c *Collection) SortsBy(key string) bool {
+	return ValidSort(key) == nil
+}
+
+func (c *Collection) PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	PrintQuotesPage(c.Name+" · "+title, quotes, columns)
+}
+
+// Collections stores one file per collection in a directory
+type Collections struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// OpenCollections uses dir, which is created on the first save
+func OpenCollections(dir string) *Collections {
+	return &Collections{dir: dir}
+}
+
+// CollectionFile turns a name into a file name, "Retro openers" into "retro-openers.json"
+func CollectionFile(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-") + COLLECTION_EXT
+}
+
+func (cs *Collections) path(name string) (string, error) {
+	file := CollectionFile(name)
+	if file == COLLECTION_EXT {
+		return "", fmt.Errorf("invalid collection name %q", name)
+	}
+	return filepath.Join(cs.dir, file), nil
+}
+
+// List returns the collections sorted by name
+func (cs *Collections) List() ([]*Collection, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(cs.dir, "*"+COLLECTION_EXT))
+	if err != nil {
+		return nil, err
+	}
+	var list []*Collection
+	for _, f := range files {
+		c := &Collection{}
+		if err := readJSONFile(f, c); err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", f, err)
+		}
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) })
+	return list, nil
+}
+
+// Get loads a collection by name, ignoring case
+func (cs *Collections) Get(name string) (*Collection, error) {
+	path, err := cs.path(name)
+	if err != nil {
+		return nil, err
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	c := &Collection{}
+	if err := readJSONFile(path, c); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no collection named %q", name)
+		}
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Exists tells whether a collection of that name is stored
+func (cs *Collections) Exists(name string) bool {
+	path, err := cs.path(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Save writes a collection, replacing the one of the same name
+func (cs *Collections) Save(c *Collection) error {
+	path, err := cs.path(c.Name)
+	if err != nil {
+		return err
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if err := os.MkdirAll(cs.dir, 0o755); err != nil {
+		return err
+	}
+	return writeJSONFile(path, c)
+}
+
+// Delete removes a collection
+func (cs *Collections) Delete(name string) error {
+	path, err := cs.path(name)
+	if err != nil {
+		return err
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no collection named %q", name)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// LINE_WIDTH is the wrapping width of Quote.Sprint
+	LINE_WIDTH = 60
+	// a filtered query reads at most this many pages of the source, the
+	// results of a larger one are marked as truncated
+	FILTER_MAX_PAGES = 50
+)
+
+// HasConstraints tells whether any length constraint is set
+func (qo *QueryOptions) HasConstraints() bool {
+	return qo.MinChars > 0 || qo.MaxChars > 0 || qo.MinWords > 0 || qo.MaxWords > 0 ||
+		qo.MaxLines > 0 || qo.FitsIn > 0
+}
+
+// Constraints describes the length constraints for display
+func (qo *QueryOptions) Constraints() []string {
+	var out []string
+	if s := describeRange(qo.MinChars, qo.MaxChars, "chars"); s != "" {
+		out = append(out, s)
+	}
+	if s := describeRange(qo.MinWords, qo.MaxWords, "words"); s != "" {
+		out = append(out, s)
+	}
+	if qo.MaxLines > 0 {
+		out = append(out, fmt.Sprintf("at most %d lines", qo.MaxLines))
+	}
+	if qo.FitsIn > 0 {
+		out = append(out, fmt.Sprintf("fits in %d chars", qo.FitsIn))
+	}
+	switch {
+	case qo.MinRating > 0 && qo.MinRating == qo.MaxRating:
+		out = append(out, fmt.Sprintf("rated %d", qo.MinRating))
+	case qo.MinRating > 0 || qo.MaxRating > 0:
+		out = append(out, "rated "+describeRange(qo.MinRating, qo.MaxRating, "stars"))
+	}
+	for _, t := range qo.Tagged {
+		out = append(out, "#"+t)
+	}
+	if qo.NoteQuery != "" {
+		out = append(out, fmt.Sprintf("note has %q", qo.NoteQuery))
+	}
+	return out
+}
+
+func describeRange(min, max int, unit string) string {
+	switch {
+	case min > 0 && max > 0:
+		return fmt.Sprintf("%d-%d %s", min, max, unit)
+	case min > 0:
+		return fmt.Sprintf("at least %d %s", min, unit)
+	case max > 0:
+		return fmt.Sprintf("at most %d %s", max, unit)
+	}
+	return ""
+}
+
+// PostText is the text a quote takes in a post: “text” — attribution
+func (q *Quote) PostText() string {
+	text := "“" + strings.Join(strings.Fields(q.Text), " ") + "”"
+	if attribution := q.Attribution(); attribution != "" {
+		text += " — " + attribution
+	}
+	return text
+}
+
+func matchesConstraints(q *Quote, qo *QueryOptions) bool {
+	if !qo.HasConstraints() {
+		return true
+	}
+	chars := utf8.RuneCountInString(strings.TrimSpace(q.Text))
+	if (qo.MinChars > 0 && chars < qo.MinChars) || (qo.MaxChars > 0 && chars > qo.MaxChars) {
+		return false
+	}
+	words := len(strings.Fields(q.Text))
+	if (qo.MinWords > 0 && words < qo.MinWords) || (qo.MaxWords > 0 && words > qo.MaxWords) {
+		return false
+	}
+	if qo.MaxLines > 0 && wrappedLines(q.Text, LINE_WIDTH) > qo.MaxLines {
+		return false
+	}
+	if qo.FitsIn > 0 && utf8.RuneCountInString(q.PostText()) > qo.FitsIn {
+		return false
+	}
+	return true
+}
+
+// wrappedLines counts the lines of text wrapped at width runes
+func wrappedLines(text string, width int) int {
+	lines := 0
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n") {
+		lines++
+		current := 0
+		for _, word := range strings.Fields(paragraph) {
+			n := utf8.RuneCountInString(word)
+			switch {
+			case current == 0:
+				current = n
+			case current+1+n <= width:
+				current += 1 + n
+			default:
+				lines++
+				current = n
+			}
+			// words longer than a line are cut
+			for current > width {
+				lines++
+				current -= width
+			}
+		}
+	}
+	return lines
+}
+
+// Constraining is implemented by sources that apply the length constraints themselves
+type Constraining interface {
+	AppliesConstraints() bool
+}
+
+// Filtered applies the length constraints to a source that cannot, and
+// the annotation filters to any source: it reads the source page by page
+// until the requested page is filled.
+type Filtered struct {
+	Sources
+
+	mu      sync.Mutex
+	query   QueryOptions
+	matches []*Quote
+	// next page of the source to read, 0 once it is exhausted
+	next int32
+	// truncated is set when the source had more pages than were read
+	truncated bool
+}
+
+// NewFiltered creates a Filtered object
+func NewFiltered(src Sources) *Filtered {
+	return &Filtered{Sources: src}
+}
+
+func (f *Filtered) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	c, ok := f.Sources.(Constraining)
+	applies := ok && c.AppliesConstraints()
+	if !options.HasAnnotationFilter() && (!options.HasConstraints() || applies) {
+		return f.Sources.Quotes(ctx, options)
+	}
+
+	page, limit := pageAndLimit(options)
+	query := *options
+	query.Page, query.Limit = 0, 0
+	// sources know nothing of annotations
+	inner := query.withoutAnnotationFilter()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !sameQuery(f.query, query) || f.next == 0 && f.matches == nil {
+		f.query, f.matches, f.next, f.truncated = query, []*Quote{}, 1, false
+		if !f.narrow(&inner, &query) {
+			f.next = 0
+		}
+	}
+
+	// the source is read until the page is full or nothing is left
+	for len(f.matches) < page*limit && f.next != 0 {
+		opt := inner
+		opt.Page, opt.Limit = f.next, DEFAULT_MIRROR_PAGE_SIZE
+		quotes, pag, err := f.Sources.Quotes(ctx, &opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, q := range quotes {
+			if matchesConstraints(q, &query) && matchesAnnotation(q, &query) {
+				f.matches = append(f.matches, q)
+			}
+		}
+		f.next++
+		if pag != nil && pag.Truncated {
+			f.truncated = true
+		}
+		if len(quotes) == 0 || pag == nil || int(opt.Page) >= pag.TotalPages {
+			f.next = 0
+		} else if opt.Page >= FILTER_MAX_PAGES {
+			f.next, f.truncated = 0, true
+		}
+	}
+
+	return streamPage(f.matches, f.next == 0, f.truncated, options)
+}
+
+// annotating is implemented by the Annotated wrapper
+type annotating interface {
+	annotations() *Annotations
+}
+
+// narrow limits inner to the authors of the annotated quotes matching the
+// annotation filter, so the source is not read through to find them. It
+// returns false when no quote can match.
+func (f *Filtered) narrow(inner, query *QueryOptions) bool {
+	a, ok := f.Sources.(annotating)
+	if !ok || a.annotations() == nil || !query.HasAnnotationFilter() {
+		return true
+	}
+	authors, known := a.annotations().annotatedAuthors(query)
+	if !known {
+		// a quote without author can only be found by reading the source
+		return true
+	}
+	if selected := query.SelectedAuthors(); len(selected) > 0 {
+		var kept []string
+		for _, author := range authors {
+			if containsFold(selected, author) {
+				kept = append(kept, author)
+			}
+		}
+		authors = kept
+	}
+	if len(authors) == 0 {
+		return false
+	}
+	inner.Author, inner.Authors = "", authors
+	return true
+}
+
+// pageAndLimit returns the requested page and its size, with their defaults
+func pageAndLimit(options *QueryOptions) (int, int) {
+	page, limit := int(options.Page), int(options.Limit)
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = LOCALDB_DEFAULT_LIMIT
+	}
+	return page, limit
+}
+
+// streamPage pages through the quotes read so far, until the stream is
+// exhausted the total is not known and one more page is announced.
+// truncated marks a stream that stopped before the end of the source.
+func streamPage(quotes []*Quote, exhausted, truncated bool, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	if exhausted {
+		page, pag, err := paginateQuotes(quotes, options)
+		if pag != nil {
+			pag.Truncated = truncated
+		}
+		return page, pag, err
+	}
+	page, limit := pageAndLimit(options)
+	start := (page - 1) * limit
+	end := start + limit
+	if start > len(quotes) {
+		start = len(quotes)
+	}
+	if end > len(quotes) {
+		end = len(quotes)
+	}
+	return quotes[start:end], &Pagination{CurrentPage: page, NextPage: page + 1, TotalPages: page + 1, Truncated: truncated}, nil
+}
+
+func (f *Filtered) SortsBy(key string) bool {
+	sorting, ok := f.Sources.(Sorting)
+	return ok && sorting.SortsBy(key)
+}
@@ -0,0 +1,259 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const (
+	EXTERNAL_RANDOM  = "random"
+	EXTERNAL_GENRES  = "genres"
+	EXTERNAL_AUTHORS = "authors"
+	EXTERNAL_QUOTES  = "quotes"
+
+	// stderr kept to explain failures of the command
+	externalStderrLimit = 4096
+)
+
+// ExternalRequest is written as one JSON line to the command's stdin
+type ExternalRequest struct {
+	ID     int           `json:"id"`
+	Method string        `json:"method"`
+	Params *QueryOptions `json:"params,omitempty"`
+}
+
+// ExternalResponse is read as one JSON line from the command's stdout
+type ExternalResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ExternalQuotes is the result of the quotes method
+type ExternalQuotes struct {
+	Quotes     []*Quote   `json:"quotes"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// External is a Sources served by any executable speaking line-delimited
+// JSON over stdin and stdout. The command is started on first use and
+// kept running between requests.
+type External struct {
+	Name    string
+	Command []string
+	Env     []string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *tailBuffer
+	nextID int
+}
+
+// NewExternal creates an External source, env entries are KEY=value pairs
+func NewExternal(name string, command []string, env []string) *External {
+	return &External{Name: name, Command: command, Env: env}
+}
+
+func (e *External) RandomQuote(ctx context.Context) (*Quote, error) {
+	q := &Quote{}
+	if err := e.call(ctx, EXTERNAL_RANDOM, nil, q); err != nil {
+		return nil, err
+	}
+	e.stamp(q)
+	return q, nil
+}
+
+func (e *External) AllGenres(ctx context.Context) ([]string, error) {
+	var genres []string
+	err := e.call(ctx, EXTERNAL_GENRES, nil, &genres)
+	return genres, err
+}
+
+func (e *External) AllAuthors(ctx context.Context) ([]string, error) {
+	var authors []string
+	err := e.call(ctx, EXTERNAL_AUTHORS, nil, &authors)
+	return authors, err
+}
+
+func (e *External) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	res := &ExternalQuotes{}
+	if err := e.call(ctx, EXTERNAL_QUOTES, options, res); err != nil {
+		return nil, nil, err
+	}
+	for _, q := range res.Quotes {
+		e.stamp(q)
+	}
+	return res.Quotes, checkedPagination(res.Pagination, options), nil
+}
+
+// checkedPagination repairs the pagination a command sent, so that the
+// current page is counted and there is at least one page
+func checkedPagination(pag Pagination, options *QueryOptions) *Pagination {
+	if pag.CurrentPage < 1 {
+		pag.CurrentPage = 1
+		if options != nil && options.Page > 1 {
+			pag.CurrentPage = int(options.Page)
+		}
+	}
+	if pag.TotalPages < pag.CurrentPage {
+		pag.TotalPages = pag.CurrentPage
+	}
+	if pag.NextPage <= pag.CurrentPage || pag.NextPage > pag.TotalPages {
+		pag.NextPage = 0
+	}
+	if pag.TotalQuotes < 0 {
+		pag.TotalQuotes = 0
+	}
+	return &pag
+}
+
+func (e *External) PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	PrintQuotesPage(title, quotes, columns)
+}
+
+// Close stops the command
+func (e *External) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stop()
+}
+
+func (e *External) stamp(q *Quote) {
+	if q.Provider == "" {
+		q.Provider = e.Name
+	}
+}
+
+func (e *External) call(ctx context.Context, method string, params *QueryOptions, result interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cmd == nil {
+		if err := e.start(); err != nil {
+			return fmt.Errorf("could not start %s: %v", e.Name, err)
+		}
+	}
+	e.nextID++
+	req := &ExternalRequest{ID: e.nextID, Method: method, Params: params}
+	line, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := e.stdin.Write(append(line, '\n')); err != nil {
+		return e.failed(err)
+	}
+
+	type reply struct {
+		line []byte
+		err  error
+	}
+	replies := make(chan reply, 1)
+	go func() {
+		line, err := e.stdout.ReadBytes('\n')
+		replies <- reply{line, err}
+	}()
+
+	var r reply
+	select {
+	case r = <-replies:
+	case <-ctx.Done():
+		// the reply may still come, the command can not be trusted anymore
+		e.stop()
+		<-replies
+		return ctx.Err()
+	}
+	if r.err != nil {
+		return e.failed(r.err)
+	}
+
+	res := &ExternalResponse{}
+	if err := json.Unmarshal(r.line, res); err != nil {
+		return fmt.Errorf("%s: invalid response: %v", e.Name, err)
+	}
+	if res.ID != req.ID {
+		e.stop()
+		return fmt.Errorf("%s: response %d does not answer request %d", e.Name, res.ID, req.ID)
+	}
+	if res.Error != "" {
+		return fmt.Errorf("%s: %s", e.Name, res.Error)
+	}
+	if err := json.Unmarshal(res.Result, result); err != nil {
+		return fmt.Errorf("%s: invalid %s result: %v", e.Name, method, err)
+	}
+	return nil
+}
+
+func (e *External) start() error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("no command configured")
+	}
+	cmd := exec.Command(e.Command[0], e.Command[1:]...)
+	cmd.Env = append(os.Environ(), e.Env...)
+	e.stderr = &tailBuffer{}
+	cmd.Stderr = e.stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	e.cmd, e.stdin, e.stdout = cmd, stdin, bufio.NewReader(stdout)
+	return nil
+}
+
+func (e *External) stop() error {
+	if e.cmd == nil {
+		return nil
+	}
+	e.stdin.Close()
+	e.cmd.Process.Kill()
+	e.cmd.Wait()
+	e.cmd = nil
+	return nil
+}
+
+// failed stops the command and reports what it wrote on stderr
+func (e *External) failed(err error) error {
+	e.stop()
+	if msg := strings.TrimSpace(e.stderr.String()); msg != "" {
+		return fmt.Errorf("%s: %v: %s", e.Name, err, msg)
+	}
+	return fmt.Errorf("%s: %v", e.Name, err)
+}
+
+// tailBuffer keeps the last bytes written to it
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > externalStderrLimit {
+		t.buf = t.buf[len(t.buf)-externalStderrLimit:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,305 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_BREAKER_FAILURES = 3
+	DEFAULT_BREAKER_COOLDOWN = 30 * time.Second
+)
+
+// Member is a source taking part in a Failover
+type Member struct {
+	Name   string
+	Source Sources
+}
+
+type FailoverOptions struct {
+	// Failures in a row that open the circuit of a member
+	Failures int
+	// Cooldown is how long an open circuit waits before it is probed again
+	Cooldown time.Duration
+	// Cache, when set, records what the primary serves and is tried first
+	Cache *Cache
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	// a single call probes whether the member recovered
+	breakerHalfOpen
+)
+
+type breaker struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+// Failover serves from its primary source and, while a circuit breaker
+// holds the primary off, from the first fallback that can answer.
+// Quotes served by a fallback carry its name in ServedBy.
+type Failover struct {
+	// Notify reports members going down and coming back
+	Notify func(message string)
+
+	members []Member
+	options FailoverOptions
+
+	mu       sync.Mutex
+	breakers []*breaker
+	// messages of background calls wait for the next foreground one
+	pending []string
+}
+
+// NewFailover creates a Failover object
+func NewFailover(primary Member, fallbacks []Member, options FailoverOptions) *Failover {
+	if options.Failures <= 0 {
+		options.Failures = DEFAULT_BREAKER_FAILURES
+	}
+	if options.Cooldown <= 0 {
+		options.Cooldown = DEFAULT_BREAKER_COOLDOWN
+	}
+	members := []Member{primary}
+	if options.Cache != nil {
+		members = append(members, Member{Name: "cache", Source: options.Cache})
+	}
+	members = append(members, fallbacks...)
+
+	f := &Failover{members: members, options: options}
+	for range members {
+		f.breakers = append(f.breakers, &breaker{})
+	}
+	return f
+}
+
+// Primary returns the source the failover protects
+func (f *Failover) Primary() Sources {
+	return f.members[0].Source
+}
+
+func (f *Failover) RandomQuote(ctx context.Context) (*Quote, error) {
+	var quote *Quote
+	err := f.try(ctx, func(i int, src Sources) (bool, error) {
+		q, err := src.RandomQuote(ctx)
+		if err != nil {
+			return false, err
+		}
+		quote = f.served(i, []*Quote{q})[0]
+		return true, nil
+	})
+	return quote, err
+}
+
+func (f *Failover) AllGenres(ctx context.Context) ([]string, error) {
+	var genres []string
+	err := f.try(ctx, func(i int, src Sources) (bool, error) {
+		var err error
+		genres, err = src.AllGenres(ctx)
+		return len(genres) > 0, err
+	})
+	return genres, err
+}
+
+func (f *Failover) AllAuthors(ctx context.Context) ([]string, error) {
+	var authors []string
+	err := f.try(ctx, func(i int, src Sources) (bool, error) {
+		var err error
+		authors, err = src.AllAuthors(ctx)
+		return len(authors) > 0, err
+	})
+	return authors, err
+}
+
+func (f *Failover) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	var quotes []*Quote
+	var pagination *Pagination
+	err := f.try(ctx, func(i int, src Sources) (bool, error) {
+		qs, pag, err := src.Quotes(ctx, options)
+		if err != nil {
+			return false, err
+		}
+		quotes, pagination = f.served(i, qs), pag
+		// the primary may rightly find nothing, a fallback may just not have it
+		return i == 0 || len(qs) > 0, nil
+	})
+	return quotes, pagination, err
+}
+
+func (f *Failover) PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	PrintQuotesPage(title, quotes, columns)
+}
+
+// Close closes the members that hold resources
+func (f *Failover) Close() error {
+	var first error
+	for _, m := range f.members {
+		if closer, ok := m.Source.(io.Closer); ok {
+			if err := closer.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// Probe checks the members whose circuit is due for a retry, so they
+// recover even when no request comes in
+func (f *Failover) Probe(ctx context.Context) {
+	for i, m := range f.members {
+		f.mu.Lock()
+		due := f.breakers[i].state == breakerOpen
+		f.mu.Unlock()
+		if !due || !f.allow(i) {
+			continue
+		}
+		_, err := m.Source.AllGenres(ctx)
+		if ctx.Err() != nil {
+			f.abandon(i)
+			return
+		}
+		f.record(ctx, i, err)
+	}
+}
+
+// ProbeEvery runs Probe in the background until ctx is done
+func (f *Failover) ProbeEvery(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				f.Probe(ctx)
+			}
+		}
+	}()
+}
+
+// try calls fn on the members in order until one serves the request.
+// fn reports whether its answer is good enough to stop there.
+func (f *Failover) try(ctx context.Context, fn func(i int, src Sources) (bool, error)) error {
+	f.flush(ctx)
+	var firstErr error
+	for i, m := range f.members {
+		if !f.allow(i) {
+			continue
+		}
+		ok, err := fn(i, m.Source)
+		if ctx.Err() != nil {
+			f.abandon(i)
+			return ctx.Err()
+		}
+		f.record(ctx, i, err)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %v", m.Name, err)
+			}
+			continue
+		}
+		if ok {
+			return nil
+		}
+	}
+	// nobody served it: report the first failure or keep the last empty answer
+	return firstErr
+}
+
+// allow tells whether member i may be called, turning an open
+// circuit half-open once the cooldown is over
+func (f *Failover) allow(i int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b := f.breakers[i]
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < f.options.Cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// another call is already probing
+		return false
+	}
+	return true
+}
+
+// abandon puts back a probe that was cancelled before it got an answer
+func (f *Failover) abandon(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if b := f.breakers[i]; b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+func (f *Failover) record(ctx context.Context, i int, err error) {
+	f.mu.Lock()
+	b := f.breakers[i]
+	name := f.members[i].Name
+	var message string
+	if err == nil {
+		if b.state != breakerClosed {
+			message = fmt.Sprintf("%s is back", name)
+		}
+		b.state, b.failures = breakerClosed, 0
+	} else {
+		b.failures++
+		if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= f.options.Failures) {
+			if b.state == breakerClosed {
+				message = fmt.Sprintf("%s is failing (%v), serving from fallbacks for %s", name, err, f.options.Cooldown)
+			}
+			b.state, b.openedAt = breakerOpen, time.Now()
+		}
+	}
+	if message != "" {
+		f.pending = append(f.pending, message)
+	}
+	f.mu.Unlock()
+	f.flush(ctx)
+}
+
+// flush hands the pending messages to Notify outside background calls
+func (f *Failover) flush(ctx context.Context) {
+	if isQuiet(ctx) {
+		return
+	}
+	f.mu.Lock()
+	pending := f.pending
+	f.pending = nil
+	f.mu.Unlock()
+	if f.Notify != nil {
+		for _, message := range pending {
+			f.Notify(message)
+		}
+	}
+}
+
+// served marks quotes with the member that served them and
+// remembers what the primary served in the cache
+func (f *Failover) served(i int, quotes []*Quote) []*Quote {
+	if i == 0 {
+		if f.options.Cache != nil {
+			// the cache is a convenience, failing to write it is not an error
+			_ = f.options.Cache.Add(quotes...)
+		}
+		return quotes
+	}
+	marked := make([]*Quote, 0, len(quotes))
+	for _, q := range quotes {
+		c := *q
+		c.ServedBy = f.members[i].Name
+		marked = append(marked, &c)
+	}
+	return marked
+}
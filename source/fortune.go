@@ -0,0 +1,380 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FORTUNE_DELIM    = '%'
+	FORTUNE_VERSION  = 2
+	FORTUNE_PROVIDER = "fortune"
+	// a text line that is just the delimiter is written with a leading space
+	FORTUNE_ESCAPED_DELIM = " %"
+
+	// strfile header flags
+	FORTUNE_FLAG_RANDOM  = 0x1
+	FORTUNE_FLAG_ORDERED = 0x2
+	FORTUNE_FLAG_ROTATED = 0x4
+)
+
+// fortuneHeader mirrors the strfile(8) .dat header, stored big-endian
+type fortuneHeader struct {
+	Version  uint32
+	NumStr   uint32
+	LongLen  uint32
+	ShortLen uint32
+	Flags    uint32
+	Delim    [4]byte
+}
+
+var (
+	fortuneAttribution = regexp.MustCompile(`^\s*(?:--|—|―)\s*(.+?)\s*$`)
+	fortuneYear        = regexp.MustCompile(`^(.*?)\s*\((\d{1,4})\)$`)
+)
+
+type fortuneFile struct {
+	path    string
+	genre   string
+	offsets []uint32
+	rotated bool
+	// delim is the byte of the delimiter lines, from the .dat header
+	delim byte
+}
+
+// Fortune is a Sources reading fortune(6) cookie files.
+// Every file is a genre; the .dat index is used when present.
+type Fortune struct {
+	files   []*fortuneFile
+	quotes  []*Quote
+	authors []string
+}
+
+// OpenFortune loads fortune files, directories are searched for cookie files
+func OpenFortune(paths ...string) (*Fortune, error) {
+	f := &Fortune{}
+	for _, p := range paths {
+		stat, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			if err := f.addFile(p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != "" {
+				continue
+			}
+			if err := f.addFile(filepath.Join(p, name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(f.files) == 0 {
+		return nil, fmt.Errorf("no fortune files found")
+	}
+	return f, f.load()
+}
+
+func (f *Fortune) RandomQuote(ctx context.Context) (*Quote, error) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if len(f.quotes) == 0 {
+		return nil, ErrQuoteNotFound
+	}
+	return f.quotes[r.Intn(len(f.quotes))], nil
+}
+
+func (f *Fortune) AllGenres(ctx context.Context) ([]string, error) {
+	var genres []string
+	for _, file := range f.files {
+		genres = append(genres, file.genre)
+	}
+	sort.Strings(genres)
+	return genres, nil
+}
+
+func (f *Fortune) AllAuthors(ctx context.Context) ([]string, error) {
+	return f.authors, nil
+}
+
+func (f *Fortune) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	var matches []*Quote
+	for _, q := range f.quotes {
+		if matchesOptions(q, options) {
+			matches = append(matches, q)
+		}
+	}
+	SortQuotes(matches, options.Sort, options.Seed)
+	return paginateQuotes(matches, options)
+}
+
+func (f *Fortune) SelectsMany() bool {
+	return true
+}
+
+func (f *Fortune) AppliesConstraints() bool {
+	return true
+}
+
+func (f *Fortune) SortsBy(key string) bool {
+	return ValidSort(key) == nil
+}
+
+func (f *Fortune) PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	PrintQuotesPage(title, quotes, columns)
+}
+
+func (f *Fortune) addFile(path string) error {
+	file := &fortuneFile{path: path, genre: filepath.Base(path), delim: FORTUNE_DELIM}
+	if err := file.readIndex(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	f.files = append(f.files, file)
+	return nil
+}
+
+func (f *Fortune) load() error {
+	authors := map[string]bool{}
+	for _, file := range f.files {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(file.offsets); i++ {
+			start, end := int(file.offsets[i]), int(file.offsets[i+1])
+			if end > len(data) || start >= end {
+				return fmt.Errorf("%s: index does not match the file, run strfile again", file.path)
+			}
+			text := trimFortuneCookie(data[start:end], file.delim)
+			if text == "" {
+				continue
+			}
+			if file.rotated {
+				text = rot13(text)
+			}
+			q := parseFortuneCookie(text)
+			q.ID = fmt.Sprintf("fortune-%s-%d", file.genre, i)
+			q.Genre = file.genre
+			q.Provider = FORTUNE_PROVIDER
+			if q.Author != "" && !authors[q.Author] {
+				authors[q.Author] = true
+				f.authors = append(f.authors, q.Author)
+			}
+			f.quotes = append(f.quotes, q)
+		}
+	}
+	sort.Strings(f.authors)
+	return nil
+}
+
+// readIndex loads the .dat offsets or builds them by scanning the file
+func (file *fortuneFile) readIndex() error {
+	if dat, err := os.Open(file.path + ".dat"); err == nil {
+		defer dat.Close()
+		header := fortuneHeader{}
+		if err := binary.Read(dat, binary.BigEndian, &header); err != nil {
+			return fmt.Errorf("invalid .dat header: %v", err)
+		}
+		if header.Delim[0] != 0 {
+			file.delim = header.Delim[0]
+		}
+		// ordered and random tables lose the end offsets, rescan those
+		if header.Flags&(FORTUNE_FLAG_ORDERED|FORTUNE_FLAG_RANDOM) == 0 {
+			file.offsets = make([]uint32, header.NumStr+1)
+			if err := binary.Read(dat, binary.BigEndian, file.offsets); err != nil {
+				return fmt.Errorf("invalid .dat offsets: %v", err)
+			}
+			file.rotated = header.Flags&FORTUNE_FLAG_ROTATED != 0
+			return nil
+		}
+		file.rotated = header.Flags&FORTUNE_FLAG_ROTATED != 0
+	}
+
+	f, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	file.offsets, _, err = scanFortuneOffsets(f, file.delim)
+	return err
+}
+
+// scanFortuneOffsets returns strfile-style offsets: the start of every
+// cookie followed by the end of the last one
+func scanFortuneOffsets(r io.Reader, delim byte) ([]uint32, fortuneHeader, error) {
+	header := fortuneHeader{Version: FORTUNE_VERSION, ShortLen: ^uint32(0)}
+	header.Delim[0] = delim
+
+	var offsets []uint32
+	var pos, start uint32
+	br := bufio.NewReader(r)
+	addCookie := func(end uint32) {
+		if end == start {
+			return
+		}
+		length := end - start
+		offsets = append(offsets, start)
+		header.NumStr++
+		if length > header.LongLen {
+			header.LongLen = length
+		}
+		if length < header.ShortLen {
+			header.ShortLen = length
+		}
+	}
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if isFortuneDelim(line, delim) {
+				addCookie(pos)
+				pos += uint32(len(line))
+				start = pos
+			} else {
+				pos += uint32(len(line))
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, header, err
+		}
+	}
+	addCookie(pos)
+	if header.NumStr == 0 {
+		header.ShortLen = 0
+	}
+	// the closing offset lets readers find the end of the last cookie
+	offsets = append(offsets, pos)
+	return offsets, header, nil
+}
+
+// WriteFortune exports quotes as a fortune file and its strfile .dat index.
+// The attribution is "-- Author (Year)", as read back by the fortune source.
+func WriteFortune(path string, quotes []*Quote) error {
+	var buf bytes.Buffer
+	for _, q := range quotes {
+		for _, line := range strings.Split(strings.TrimSpace(q.Text), "\n") {
+			if isFortuneDelim([]byte(line), FORTUNE_DELIM) {
+				line = FORTUNE_ESCAPED_DELIM
+			}
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+		attribution := q.Author
+		if q.Year != 0 {
+			attribution = strings.TrimSpace(fmt.Sprintf("%s (%d)", attribution, q.Year))
+		}
+		if attribution != "" {
+			fmt.Fprintf(&buf, "\t\t-- %s\n", attribution)
+		}
+		buf.WriteString("%\n")
+	}
+	offsets, header, err := scanFortuneOffsets(bytes.NewReader(buf.Bytes()), FORTUNE_DELIM)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	var dat bytes.Buffer
+	if err := binary.Write(&dat, binary.BigEndian, header); err != nil {
+		return err
+	}
+	if err := binary.Write(&dat, binary.BigEndian, offsets); err != nil {
+		return err
+	}
+	return os.WriteFile(path+".dat", dat.Bytes(), 0o644)
+}
+
+func isFortuneDelim(line []byte, delim byte) bool {
+	line = bytes.TrimRight(line, "\r\n")
+	return len(line) == 1 && line[0] == delim
+}
+
+// trimFortuneCookie drops the delimiter lines around a cookie: offsets of
+// the .dat end after them, and strfile starts empty cookies on them
+func trimFortuneCookie(b []byte, delim byte) string {
+	for {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 || !isFortuneDelim(b[:i+1], delim) {
+			break
+		}
+		b = b[i+1:]
+	}
+	for {
+		b = bytes.TrimRight(b, "\r\n")
+		i := bytes.LastIndexByte(b, '\n')
+		if !isFortuneDelim(b[i+1:], delim) {
+			return string(b)
+		}
+		b = b[:i+1]
+	}
+}
+
+// parseFortuneCookie splits a trailing "-- Author (Year)" attribution from the text
+func parseFortuneCookie(text string) *Quote {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimRight(line, "\r") == FORTUNE_ESCAPED_DELIM {
+			lines[i] = string(FORTUNE_DELIM)
+		}
+	}
+	text = strings.Join(lines, "\n")
+	q := &Quote{Text: strings.TrimSpace(text)}
+	last := len(lines) - 1
+	if last > 0 {
+		if m := fortuneAttribution.FindStringSubmatch(lines[last]); m != nil {
+			q.Author = m[1]
+			if y := fortuneYear.FindStringSubmatch(q.Author); y != nil {
+				q.Author = y[1]
+				q.Year, _ = strconv.Atoi(y[2])
+			}
+			q.Text = strings.TrimSpace(strings.Join(lines[:last], "\n"))
+		}
+	}
+	return q
+}
+
+func matchesOptions(q *Quote, options *QueryOptions) bool {
+	if !matchesSelection(q, options) {
+		return false
+	}
+	if options.Query != "" && !strings.Contains(strings.ToLower(q.Text), strings.ToLower(options.Query)) {
+		return false
+	}
+	return matchesConstraints(q, options)
+}
+
+func rot13(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return 'a' + (r-'a'+13)%26
+		case r >= 'A' && r <= 'Z':
+			return 'A' + (r-'A'+13)%26
+		}
+		return r
+	}, s)
+}
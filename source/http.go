@@ -0,0 +1,140 @@
+package source
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_HTTP_TIMEOUT   = time.Minute
+	DEFAULT_USER_AGENT     = "goqu (+https://github.com/custompointofview/goqu)"
+	DEFAULT_API_KEY_HEADER = "X-API-Key"
+)
+
+// HTTPOptions configures the client shared by the HTTP based sources
+type HTTPOptions struct {
+	// Proxy is an http, https or socks5 URL, empty to follow $HTTPS_PROXY
+	Proxy string
+	// CACert is a PEM bundle trusted on top of the system roots
+	CACert string
+	// ClientCert and ClientKey are the PEM files of a TLS client certificate
+	ClientCert string
+	ClientKey  string
+	UserAgent  string
+	// Token is sent as a bearer token
+	Token string
+	// APIKey is sent in APIKeyHeader
+	APIKey       string
+	APIKeyHeader string
+	// CredentialHost is the only host receiving Token and APIKey, empty
+	// for the host first requested; redirects elsewhere go without them
+	CredentialHost string
+	// Timeout limits every request, including reading the response
+	Timeout time.Duration
+}
+
+// NewHTTPClient builds a client from opts, the zero value gives the defaults
+func NewHTTPClient(opts HTTPOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if opts.CACert != "" || opts.ClientCert != "" || opts.ClientKey != "" {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if opts.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACert)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %v", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	headers := &headerTransport{
+		next:         transport,
+		userAgent:    opts.UserAgent,
+		token:        opts.Token,
+		apiKey:       opts.APIKey,
+		apiKeyHeader: opts.APIKeyHeader,
+		host:         opts.CredentialHost,
+	}
+	if headers.userAgent == "" {
+		headers.userAgent = DEFAULT_USER_AGENT
+	}
+	if headers.apiKeyHeader == "" {
+		headers.apiKeyHeader = DEFAULT_API_KEY_HEADER
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_HTTP_TIMEOUT
+	}
+	return &http.Client{Timeout: timeout, Transport: headers}, nil
+}
+
+// headerTransport adds the identification and credentials to every request
+type headerTransport struct {
+	next         http.RoundTripper
+	userAgent    string
+	token        string
+	apiKey       string
+	apiKeyHeader string
+	host         string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	if !t.sendsCredentials(req) {
+		req.Header.Del("Authorization")
+		req.Header.Del(t.apiKeyHeader)
+		return t.next.RoundTrip(req)
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	if t.apiKey != "" {
+		req.Header.Set(t.apiKeyHeader, t.apiKey)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// sendsCredentials tells whether req goes to the host the credentials are
+// for, redirects pass through here too so each hop is checked
+func (t *headerTransport) sendsCredentials(req *http.Request) bool {
+	host := t.host
+	if host == "" {
+		// the request that started the redirects
+		first := req
+		for first.Response != nil && first.Response.Request != nil {
+			first = first.Response.Request
+		}
+		host = first.URL.Host
+	}
+	return strings.EqualFold(req.URL.Host, host)
+}
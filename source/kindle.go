@@ -0,0 +1,174 @@
+package source
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	KINDLE_SEPARATOR = "=========="
+	KINDLE_PROVIDER  = "Kindle"
+)
+
+// Kindle writes the kind of clipping in the reader's language,
+// e.g. "- Your Bookmark on page 3" or "- Ihre Notiz bei Position 12".
+var (
+	kindleBookmarkWords = []string{"bookmark", "lesezeichen", "signet", "marcador", "segnalibro", "bladwijzer", "закладка", "ブックマーク"}
+	kindleNoteWords     = []string{"note", "notiz", "nota", "notitie", "заметка", "メモ"}
+	// the start of "Location 120-125", "Position 120-125", "位置No. 120-125"...
+	kindleLocation = regexp.MustCompile(`(?i)(?:location|loc\.|position|posición|posizione|emplacement|locatie|положение|место|位置no\.)\s*(\d+)`)
+	// generational and academic suffixes that follow a comma but are no first name
+	kindleNameSuffixes = []string{"jr", "jr.", "sr", "sr.", "ii", "iii", "iv", "phd", "ph.d.", "md", "m.d."}
+)
+
+// ParseKindleClippings reads a Kindle "My Clippings.txt" export.
+// Highlights become quotes with the book title as work and genre, bookmarks
+// and notes are skipped and re-highlighted passages keep their longest version.
+func ParseKindleClippings(r io.Reader) ([]*Quote, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var quotes []*Quote
+	var entry []string
+	flush := func() {
+		if q := parseKindleEntry(entry); q != nil {
+			quotes = appendKindleQuote(quotes, q)
+		}
+		entry = entry[:0]
+	}
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), "\r")
+		if strings.TrimSpace(line) == KINDLE_SEPARATOR {
+			flush()
+			continue
+		}
+		entry = append(entry, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return quotes, nil
+}
+
+func parseKindleEntry(lines []string) *Quote {
+	// title, metadata, empty line, text...
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) < 3 {
+		return nil
+	}
+	if kindleKindIn(lines[1], kindleBookmarkWords) || kindleKindIn(lines[1], kindleNoteWords) {
+		return nil
+	}
+	text := strings.TrimSpace(strings.Join(lines[2:], "\n"))
+	if text == "" {
+		return nil
+	}
+
+	title, author := parseKindleTitle(lines[0])
+	// a highlight keeps its id when it is extended, it is known by where
+	// it starts in the book
+	key := text
+	if m := kindleLocation.FindStringSubmatch(lines[1]); m != nil {
+		key = "@" + m[1]
+	}
+	sum := sha1.Sum([]byte(title + "\x00" + author + "\x00" + key))
+	return &Quote{
+		ID:       "kindle-" + hex.EncodeToString(sum[:8]),
+		Text:     text,
+		Author:   author,
+		Genre:    title,
+		Work:     title,
+		Provider: KINDLE_PROVIDER,
+	}
+}
+
+// kindleKindIn checks the first words of the metadata line, before the location.
+// Languages written without spaces, e.g. "位置No. 80のメモ", are searched
+// in the whole line.
+func kindleKindIn(meta string, words []string) bool {
+	kind := strings.ToLower(meta)
+	for _, w := range words {
+		if !isSpaced(w) && strings.Contains(kind, w) {
+			return true
+		}
+	}
+	if i := strings.Index(kind, "|"); i >= 0 {
+		kind = kind[:i]
+	}
+	fields := strings.Fields(strings.TrimLeft(kind, "- "))
+	if len(fields) > 3 {
+		fields = fields[:3]
+	}
+	for _, f := range fields {
+		for _, w := range words {
+			if f == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSpaced tells whether w belongs to a script separating words with spaces
+func isSpaced(w string) bool {
+	for _, r := range w {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseKindleTitle splits "Title (Author)"; authors written
+// as "Last, First" are turned around, "King, Jr." is not.
+func parseKindleTitle(line string) (title, author string) {
+	line = strings.TrimSpace(line)
+	if !strings.HasSuffix(line, ")") {
+		return line, ""
+	}
+	open := strings.LastIndex(line, "(")
+	if open < 0 {
+		return line, ""
+	}
+	title = strings.TrimSpace(line[:open])
+	author = strings.TrimSpace(line[open+1 : len(line)-1])
+	if parts := strings.Split(author, ","); len(parts) == 2 && !strings.Contains(author, ";") &&
+		!containsFold(kindleNameSuffixes, strings.TrimSpace(parts[1])) {
+		author = strings.TrimSpace(parts[1]) + " " + strings.TrimSpace(parts[0])
+	}
+	if title == "" {
+		return author, ""
+	}
+	return title, author
+}
+
+// appendKindleQuote drops passages already contained in a highlight
+// of the same book and replaces shorter ones the new highlight extends.
+// A later highlight at the same location replaces the earlier one.
+func appendKindleQuote(quotes []*Quote, q *Quote) []*Quote {
+	for i, other := range quotes {
+		if other.Work != q.Work || other.Author != q.Author {
+			continue
+		}
+		if other.ID == q.ID {
+			quotes[i] = q
+			return quotes
+		}
+		if strings.Contains(other.Text, q.Text) {
+			return quotes
+		}
+		if strings.Contains(q.Text, other.Text) {
+			quotes[i] = q
+			return quotes
+		}
+	}
+	return append(quotes, q)
+}
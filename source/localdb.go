@@ -0,0 +1,672 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mrand "math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LOCALDB_DEFAULT_LIMIT = 10
+	// bump whenever localDBIndex changes to rebuild cached indexes
+	LOCALDB_INDEX_VERSION = 2
+
+	localDBOpPut    = "put"
+	localDBOpDelete = "del"
+)
+
+var ErrQuoteNotFound = errors.New("quote not found")
+
+type localDBRecord struct {
+	Op    string `json:"op"`
+	ID    string `json:"id"`
+	Quote *Quote `json:"quote,omitempty"`
+}
+
+type localDBEntry struct {
+	Offset int64
+	Size   int
+	Author string
+	Genres []string
+}
+
+// localDBIndex is kept in memory and cached next to the data file.
+// Quotes themselves stay on disk and are read on demand.
+type localDBIndex struct {
+	Version int
+	// Size of the data file the index was built from
+	Size    int64
+	Entries map[string]localDBEntry
+	// display names by lower-cased key
+	Authors map[string]string
+	Genres  map[string]string
+}
+
+// LocalDB is a Sources backed by a single append-only file.
+// Every write appends a record; Compact drops superseded records.
+type LocalDB struct {
+	path string
+
+	mu    sync.RWMutex
+	file  *os.File
+	index *localDBIndex
+
+	// sorted ID lists, rebuilt lazily after writes
+	ids      []string
+	byAuthor map[string][]string
+	byGenre  map[string][]string
+}
+
+// OpenLocalDB opens or creates the database file at path
+func OpenLocalDB(path string) (*LocalDB, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	db := &LocalDB{path: path, file: f}
+	if err := db.loadIndex(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not open %s: %v", path, err)
+	}
+	return db, nil
+}
+
+// Close persists the index and closes the data file
+func (db *LocalDB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.file == nil {
+		return nil
+	}
+	// the index is a cache, it is only saved when the file can be locked
+	var err error
+	if unlock, lerr := db.lock(); lerr == nil {
+		err = db.saveIndex()
+		unlock()
+	}
+	if cerr := db.file.Close(); err == nil {
+		err = cerr
+	}
+	db.file = nil
+	return err
+}
+
+// Len returns the number of stored quotes
+func (db *LocalDB) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.index.Entries)
+}
+
+// Get reads a single quote by ID
+func (db *LocalDB) Get(id string) (*Quote, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.get(id)
+}
+
+// Put creates or replaces a quote. Quotes without an ID get a generated one.
+func (db *LocalDB) Put(q *Quote) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	unlock, err := db.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if q.ID == "" {
+		q.ID = newQuoteID()
+	}
+	return db.append(&localDBRecord{Op: localDBOpPut, ID: q.ID, Quote: q})
+}
+
+// Delete removes a quote by ID
+func (db *LocalDB) Delete(id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	unlock, err := db.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, ok := db.index.Entries[id]; !ok {
+		return ErrQuoteNotFound
+	}
+	return db.append(&localDBRecord{Op: localDBOpDelete, ID: id})
+}
+
+// IDs returns all quote IDs in order
+func (db *LocalDB) IDs() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.buildLists()
+	return append([]string(nil), db.ids...)
+}
+
+// Compact rewrites the data file keeping only live quotes
+func (db *LocalDB) Compact() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	unlock, err := db.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	db.buildLists()
+
+	tmpPath := db.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	index := newLocalDBIndex()
+	w := bufio.NewWriter(tmp)
+	for _, id := range db.ids {
+		q, err := db.get(id)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		line, err := json.Marshal(&localDBRecord{Op: localDBOpPut, ID: id, Quote: q})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		line = append(line, '\n')
+		index.put(q, index.Size, len(line))
+		index.Size += int64(len(line))
+		if _, err := w.Write(line); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	db.file.Close()
+	if err := os.Rename(tmpPath, db.path); err != nil {
+		tmp.Close()
+		db.file, _ = os.OpenFile(db.path, os.O_RDWR, 0o644)
+		return err
+	}
+	db.file = tmp
+	db.index = index
+	return db.saveIndex()
+}
+
+func (db *LocalDB) RandomQuote(ctx context.Context) (*Quote, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.buildLists()
+	if len(db.ids) == 0 {
+		return nil, ErrQuoteNotFound
+	}
+	r := mrand.New(mrand.NewSource(time.Now().UnixNano()))
+	return db.get(db.ids[r.Intn(len(db.ids))])
+}
+
+func (db *LocalDB) AllGenres(ctx context.Context) ([]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.buildLists()
+	return displayNames(db.byGenre, db.index.Genres), nil
+}
+
+func (db *LocalDB) AllAuthors(ctx context.Context) ([]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.buildLists()
+	return displayNames(db.byAuthor, db.index.Authors), nil
+}
+
+func (db *LocalDB) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.buildLists()
+
+	candidates := db.ids
+	authors, genres := options.SelectedAuthors(), options.SelectedGenres()
+	switch {
+	case len(authors) > 0 && len(genres) > 0:
+		candidates = intersectSorted(lookupAll(db.byAuthor, authors), lookupAll(db.byGenre, genres))
+	case len(authors) > 0:
+		candidates = lookupAll(db.byAuthor, authors)
+	case len(genres) > 0:
+		candidates = lookupAll(db.byGenre, genres)
+	}
+	if len(options.ExcludeAuthors) > 0 {
+		candidates = subtractSorted(candidates, lookupAll(db.byAuthor, options.ExcludeAuthors))
+	}
+	if len(options.ExcludeGenres) > 0 {
+		candidates = subtractSorted(candidates, lookupAll(db.byGenre, options.ExcludeGenres))
+	}
+
+	// text search and length constraints have no index and read the candidates
+	if options.Query != "" || options.HasConstraints() {
+		query := strings.ToLower(options.Query)
+		var matches []string
+		for _, id := range candidates {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			q, err := db.get(id)
+			if err != nil {
+				return nil, nil, err
+			}
+			if strings.Contains(strings.ToLower(q.Text), query) && matchesConstraints(q, options) {
+				matches = append(matches, id)
+			}
+		}
+		candidates = matches
+	}
+
+	if options.Sort != "" {
+		quotes := make([]*Quote, 0, len(candidates))
+		for _, id := range candidates {
+			q, err := db.get(id)
+			if err != nil {
+				return nil, nil, err
+			}
+			quotes = append(quotes, q)
+		}
+		SortQuotes(quotes, options.Sort, options.Seed)
+		return paginateQuotes(quotes, options)
+	}
+
+	ids, pag := paginate(candidates, options)
+	quotes := make([]*Quote, 0, len(ids))
+	for _, id := range ids {
+		q, err := db.get(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, pag, nil
+}
+
+func (db *LocalDB) SelectsMany() bool {
+	return true
+}
+
+func (db *LocalDB) AppliesConstraints() bool {
+	return true
+}
+
+func (db *LocalDB) SortsBy(key string) bool {
+	return ValidSort(key) == nil
+}
+
+func (db *LocalDB) PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	PrintQuotesPage(title, quotes, columns)
+}
+
+func (db *LocalDB) get(id string) (*Quote, error) {
+	entry, ok := db.index.Entries[id]
+	if !ok {
+		return nil, ErrQuoteNotFound
+	}
+	buf := make([]byte, entry.Size)
+	if _, err := db.file.ReadAt(buf, entry.Offset); err != nil {
+		return nil, err
+	}
+	rec := &localDBRecord{}
+	if err := json.Unmarshal(buf, rec); err != nil {
+		return nil, fmt.Errorf("corrupt record %s: %v", id, err)
+	}
+	return rec.Quote, nil
+}
+
+func (db *LocalDB) append(rec *localDBRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	offset := db.index.Size
+	if _, err := db.file.WriteAt(line, offset); err != nil {
+		return err
+	}
+	db.index.Size += int64(len(line))
+	db.index.apply(rec, offset, len(line))
+	db.ids = nil
+	return nil
+}
+
+func (db *LocalDB) loadIndex() error {
+	stat, err := db.file.Stat()
+	if err != nil {
+		return err
+	}
+	// use the cached index only if it matches the data file
+	if index := db.cachedIndex(stat.Size()); index != nil {
+		db.index = index
+		return nil
+	}
+	db.index = newLocalDBIndex()
+	size, err := db.scan(db.index)
+	if err != nil {
+		return err
+	}
+	if db.index.Size < size {
+		// a partial record, from a writer that died or is still writing
+		unlock, err := LockFile(db.path)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return db.catchUp()
+	}
+	return nil
+}
+
+// lock takes the lock other goqu processes writing the file share, and
+// reads what they wrote since. The returned function releases it.
+func (db *LocalDB) lock() (func(), error) {
+	unlock, err := LockFile(db.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.catchUp(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// catchUp applies the records other processes appended to the file and
+// follows a file they compacted. It is called with the file lock held.
+func (db *LocalDB) catchUp() error {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return err
+	}
+	current, err := db.file.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, current) || info.Size() < db.index.Size {
+		f, err := os.OpenFile(db.path, os.O_RDWR, 0o644)
+		if err != nil {
+			return err
+		}
+		db.file.Close()
+		db.file = f
+		db.index = db.cachedIndex(info.Size())
+		if db.index == nil {
+			db.index = newLocalDBIndex()
+		}
+	}
+	size, err := db.scan(db.index)
+	if err != nil {
+		return err
+	}
+	if db.index.Size < size {
+		// appends go at index.Size, cut the partial record so that no
+		// stale bytes follow them and the cached index matches the file
+		if err := db.file.Truncate(db.index.Size); err != nil {
+			return fmt.Errorf("could not drop the partial record at offset %d: %v", db.index.Size, err)
+		}
+	}
+	db.ids = nil
+	return nil
+}
+
+// scan applies the complete records past index.Size to index and
+// returns the size of the file
+func (db *LocalDB) scan(index *localDBIndex) (int64, error) {
+	stat, err := db.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(io.NewSectionReader(db.file, index.Size, stat.Size()-index.Size))
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// a partially written trailing record is left out
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		rec := &localDBRecord{}
+		if err := json.Unmarshal(line, rec); err != nil {
+			return 0, fmt.Errorf("corrupt record at offset %d: %v", index.Size, err)
+		}
+		index.apply(rec, index.Size, len(line))
+		index.Size += int64(len(line))
+	}
+	return stat.Size(), nil
+}
+
+// cachedIndex reads the index cached for a data file of size bytes,
+// nil when there is none
+func (db *LocalDB) cachedIndex(size int64) *localDBIndex {
+	f, err := os.Open(db.path + ".idx")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	index := &localDBIndex{}
+	if err := gob.NewDecoder(f).Decode(index); err != nil || index.Version != LOCALDB_INDEX_VERSION || index.Size != size {
+		return nil
+	}
+	return index
+}
+
+func (db *LocalDB) saveIndex() error {
+	// a temporary file of its own, processes closing the same file race
+	f, err := os.CreateTemp(filepath.Dir(db.path), filepath.Base(db.path)+".idx.*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(db.index); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), db.path+".idx")
+}
+
+func (db *LocalDB) buildLists() {
+	if db.ids != nil {
+		return
+	}
+	db.ids = make([]string, 0, len(db.index.Entries))
+	db.byAuthor = map[string][]string{}
+	db.byGenre = map[string][]string{}
+	for id, e := range db.index.Entries {
+		db.ids = append(db.ids, id)
+		db.byAuthor[e.Author] = append(db.byAuthor[e.Author], id)
+		for _, g := range e.Genres {
+			db.byGenre[g] = append(db.byGenre[g], id)
+		}
+	}
+	sort.Strings(db.ids)
+	for _, ids := range db.byAuthor {
+		sort.Strings(ids)
+	}
+	for _, ids := range db.byGenre {
+		sort.Strings(ids)
+	}
+}
+
+func newLocalDBIndex() *localDBIndex {
+	return &localDBIndex{
+		Entries: map[string]localDBEntry{},
+		Authors: map[string]string{},
+		Version: LOCALDB_INDEX_VERSION,
+		Genres:  map[string]string{},
+	}
+}
+
+func (idx *localDBIndex) apply(rec *localDBRecord, offset int64, size int) {
+	switch rec.Op {
+	case localDBOpPut:
+		idx.put(rec.Quote, offset, size)
+	case localDBOpDelete:
+		delete(idx.Entries, rec.ID)
+	}
+}
+
+func (idx *localDBIndex) put(q *Quote, offset int64, size int) {
+	author := strings.ToLower(q.Author)
+	entry := localDBEntry{Offset: offset, Size: size, Author: author}
+	if _, ok := idx.Authors[author]; !ok && author != "" {
+		idx.Authors[author] = q.Author
+	}
+	seen := map[string]bool{}
+	for _, g := range q.Genres() {
+		genre := strings.ToLower(g)
+		if seen[genre] {
+			continue
+		}
+		seen[genre] = true
+		entry.Genres = append(entry.Genres, genre)
+		if _, ok := idx.Genres[genre]; !ok {
+			idx.Genres[genre] = g
+		}
+	}
+	idx.Entries[q.ID] = entry
+}
+
+// pageBounds computes the slice of a result the way QuoteGarden pages it
+func pageBounds(total int, options *QueryOptions) (start, end int, pag *Pagination) {
+	limit := int(options.Limit)
+	if limit <= 0 {
+		limit = LOCALDB_DEFAULT_LIMIT
+	}
+	page := int(options.Page)
+	if page <= 0 {
+		page = 1
+	}
+	pag = &Pagination{
+		CurrentPage: page,
+		TotalPages:  int(math.Ceil(float64(total) / float64(limit))),
+		TotalQuotes: total,
+	}
+	if page < pag.TotalPages {
+		pag.NextPage = page + 1
+	}
+	start = (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end = start + limit
+	if end > total {
+		end = total
+	}
+	return start, end, pag
+}
+
+func paginate(ids []string, options *QueryOptions) ([]string, *Pagination) {
+	start, end, pag := pageBounds(len(ids), options)
+	return ids[start:end], pag
+}
+
+func intersectSorted(a, b []string) []string {
+	var out []string
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
+
+// unionSorted merges two sorted lists without duplicates
+func unionSorted(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			out = append(out, a[i])
+			i++
+		default:
+			out = append(out, b[j])
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
+}
+
+// subtractSorted returns the ids of a that are not in b
+func subtractSorted(a, b []string) []string {
+	var out []string
+	j := 0
+	for _, id := range a {
+		for j < len(b) && b[j] < id {
+			j++
+		}
+		if j < len(b) && b[j] == id {
+			continue
+		}
+		out = append(out, id)
+	}
+	return out
+}
+
+// lookupAll returns the ids listed under any of the keys
+func lookupAll(lists map[string][]string, keys []string) []string {
+	var out []string
+	for _, k := range keys {
+		out = unionSorted(out, lists[strings.ToLower(k)])
+	}
+	return out
+}
+
+// displayNames returns the original spelling of every non-empty key in use
+func displayNames(keys map[string][]string, names map[string]string) []string {
+	values := make([]string, 0, len(keys))
+	for k := range keys {
+		if k != "" {
+			values = append(values, names[k])
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func newQuoteID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,53 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	LOCK_RETRY   = 20 * time.Millisecond
+	LOCK_TIMEOUT = 10 * time.Second
+	// a lock older than this was left by a process that died holding it
+	LOCK_STALE = time.Minute
+)
+
+// LockFile takes a lock on path shared by the goqu processes, by creating
+// path.lock. The returned function releases it.
+func LockFile(path string) (func(), error) {
+	return LockFileContext(context.Background(), path)
+}
+
+// LockFileContext is LockFile giving up waiting when ctx is done
+func LockFileContext(ctx context.Context, path string) (func(), error) {
+	lock := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lock), 0o755); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(LOCK_TIMEOUT)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > LOCK_STALE {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked, remove %s if no other goqu is running", path, lock)
+		}
+		select {
+		case <-time.After(LOCK_RETRY):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package source
+
+// The merge rules reconcile two copies of the user data changed apart
+// from a common base, e.g. on two machines. Each field keeps the side
+// that changed it; when both changed the same field, the copy updated
+// last wins. Sets and lists keep what either side added and drop what
+// either side removed.
+
+// MergeAnnotations merges the annotations of a quote, a nil base means
+// both sides added it
+func MergeAnnotations(base, ours, theirs *Annotation) *Annotation {
+	if ours == nil {
+		return theirs
+	}
+	if theirs == nil {
+		return ours
+	}
+	if base == nil {
+		base = &Annotation{}
+	}
+	oursNewer := !theirs.UpdatedAt.After(ours.UpdatedAt)
+	merged := *ours
+	merged.Rating = mergeInt(base.Rating, ours.Rating, theirs.Rating, oursNewer)
+	merged.Note = mergeString(base.Note, ours.Note, theirs.Note, oursNewer)
+	merged.Text = mergeString(base.Text, ours.Text, theirs.Text, oursNewer)
+	merged.Author = mergeString(base.Author, ours.Author, theirs.Author, oursNewer)
+	merged.Tags = mergeSet(base.Tags, ours.Tags, theirs.Tags)
+	if !oursNewer {
+		merged.UpdatedAt = theirs.UpdatedAt
+	}
+	return &merged
+}
+
+// MergeCollections merges two copies of a collection, the quotes keep
+// the order of the copy updated last
+func MergeCollections(base, ours, theirs *Collection) *Collection {
+	if ours == nil {
+		return theirs
+	}
+	if theirs == nil {
+		return ours
+	}
+	if base == nil {
+		base = &Collection{}
+	}
+	oursNewer := !theirs.UpdatedAt.After(ours.UpdatedAt)
+	merged := *ours
+	merged.Description = mergeString(base.Description, ours.Description, theirs.Description, oursNewer)
+	first, second := ours.Items, theirs.Items
+	if !oursNewer {
+		first, second = second, first
+		merged.UpdatedAt = theirs.UpdatedAt
+	}
+	merged.Items = mergeQuoteLists(base.Items, first, second)
+	if theirs.CreatedAt.Before(ours.CreatedAt) {
+		merged.CreatedAt = theirs.CreatedAt
+	}
+	return &merged
+}
+
+func mergeString(base, ours, theirs string, oursNewer bool) string {
+	if ours == base || (theirs != base && !oursNewer) {
+		return theirs
+	}
+	return ours
+}
+
+func mergeInt(base, ours, theirs int, oursNewer bool) int {
+	if ours == base || (theirs != base && !oursNewer) {
+		return theirs
+	}
+	return ours
+}
+
+// mergeSet merges sets of words, ignoring case
+func mergeSet(base, ours, theirs []string) []string {
+	var merged []string
+	for _, s := range ours {
+		if !(containsFold(base, s) && !containsFold(theirs, s)) {
+			merged = append(merged, s)
+		}
+	}
+	for _, s := range theirs {
+		if !containsFold(base, s) && !containsFold(merged, s) {
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
+
+// mergeQuoteLists keeps the order of first, then appends the quotes only
+// second added
+func mergeQuoteLists(base, first, second []*Quote) []*Quote {
+	keys := func(quotes []*Quote) map[string]bool {
+		m := make(map[string]bool, len(quotes))
+		for _, q := range quotes {
+			m[collectionKey(q)] = true
+		}
+		return m
+	}
+	inBase, inFirst, inSecond := keys(base), keys(first), keys(second)
+	merged := []*Quote{}
+	for _, q := range first {
+		if key := collectionKey(q); !inBase[key] || inSecond[key] {
+			merged = append(merged, q)
+		}
+	}
+	for _, q := range second {
+		if key := collectionKey(q); !inBase[key] && !inFirst[key] {
+			merged = append(merged, q)
+		}
+	}
+	return merged
+}
+
+// MergeQuotes merges two copies of a quote of the library, when both
+// changed a field the copy fetched last wins, ours when neither tells
+func MergeQuotes(base, ours, theirs *Quote) *Quote {
+	if ours == nil {
+		return theirs
+	}
+	if theirs == nil {
+		return ours
+	}
+	if base == nil {
+		base = &Quote{}
+	}
+	oursNewer := !theirs.FetchedAt.After(ours.FetchedAt)
+	merged := *ours
+	merged.Text = mergeString(base.Text, ours.Text, theirs.Text, oursNewer)
+	merged.Author = mergeString(base.Author, ours.Author, theirs.Author, oursNewer)
+	merged.Genre = mergeString(base.Genre, ours.Genre, theirs.Genre, oursNewer)
+	merged.Work = mergeString(base.Work, ours.Work, theirs.Work, oursNewer)
+	merged.Year = mergeInt(base.Year, ours.Year, theirs.Year, oursNewer)
+	merged.Language = mergeString(base.Language, ours.Language, theirs.Language, oursNewer)
+	merged.URL = mergeString(base.URL, ours.URL, theirs.URL, oursNewer)
+	merged.Provider = mergeString(base.Provider, ours.Provider, theirs.Provider, oursNewer)
+	merged.Tags = mergeSet(base.Tags, ours.Tags, theirs.Tags)
+	if !oursNewer {
+		merged.FetchedAt = theirs.FetchedAt
+	}
+	return &merged
+}
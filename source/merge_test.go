@@ -0,0 +1,262 @@
+package source
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	mergeT0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mergeT1 = mergeT0.Add(time.Hour)
+	mergeT2 = mergeT0.Add(2 * time.Hour)
+)
+
+func quotesByID(ids ...string) []*Quote {
+	quotes := []*Quote{}
+	for _, id := range ids {
+		quotes = append(quotes, &Quote{ID: id, Text: "text of " + id})
+	}
+	return quotes
+}
+
+func quoteIDs(quotes []*Quote) string {
+	ids := make([]string, 0, len(quotes))
+	for _, q := range quotes {
+		ids = append(ids, q.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestMergeSet(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs []string
+		want               []string
+	}{
+		{"unchanged", []string{"a"}, []string{"a"}, []string{"a"}, []string{"a"}},
+		{"both add", nil, []string{"a"}, []string{"b"}, []string{"a", "b"}},
+		{"same add once", nil, []string{"a"}, []string{"A"}, []string{"a"}},
+		{"ours removes", []string{"a", "b"}, []string{"b"}, []string{"a", "b"}, []string{"b"}},
+		{"theirs removes", []string{"a", "b"}, []string{"a", "b"}, []string{"a"}, []string{"a"}},
+		{"removed and added", []string{"a"}, []string{"b"}, []string{"a", "c"}, []string{"b", "c"}},
+		{"case of the removal", []string{"Go"}, []string{"Go"}, []string{"go"}, []string{"Go"}},
+		{"all removed", []string{"a"}, nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeSet(tt.base, tt.ours, tt.theirs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeSet(%v, %v, %v) = %v, want %v", tt.base, tt.ours, tt.theirs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeQuoteLists(t *testing.T) {
+	tests := []struct {
+		name                string
+		base, first, second string
+		want                string
+	}{
+		{"unchanged", "a,b", "a,b", "a,b", "a,b"},
+		{"first order wins", "a,b,c", "c,b,a", "a,b,c", "c,b,a"},
+		{"second adds at the end", "a,b", "b,a", "a,b,c", "b,a,c"},
+		{"both add", "a", "a,b", "a,c", "a,b,c"},
+		{"both add the same", "a", "a,b", "b,a", "a,b"},
+		{"first removes", "a,b,c", "a,c", "a,b,c", "a,c"},
+		{"second removes", "a,b,c", "a,b,c", "c,b", "b,c"},
+		{"removed on both sides", "a,b", "a", "a", "a"},
+		{"no base", "", "a", "b", "a,b"},
+		{"all removed", "a", "", "a", ""},
+	}
+	split := func(s string) []*Quote {
+		if s == "" {
+			return []*Quote{}
+		}
+		return quotesByID(strings.Split(s, ",")...)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quoteIDs(mergeQuoteLists(split(tt.base), split(tt.first), split(tt.second)))
+			if got != tt.want {
+				t.Errorf("mergeQuoteLists(%s, %s, %s) = %s, want %s", tt.base, tt.first, tt.second, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeAnnotations(t *testing.T) {
+	base := &Annotation{Rating: 3, Note: "base", Tags: []string{"a"}, Text: "t", UpdatedAt: mergeT0}
+	with := func(change func(a *Annotation)) *Annotation {
+		a := *base
+		a.Tags = append([]string(nil), base.Tags...)
+		change(&a)
+		return &a
+	}
+	tests := []struct {
+		name               string
+		base, ours, theirs *Annotation
+		want               *Annotation
+	}{
+		{
+			name:   "added on one side",
+			ours:   nil,
+			theirs: base,
+			want:   base,
+		},
+		{
+			name:   "different fields",
+			base:   base,
+			ours:   with(func(a *Annotation) { a.Rating, a.UpdatedAt = 5, mergeT1 }),
+			theirs: with(func(a *Annotation) { a.Note, a.UpdatedAt = "theirs", mergeT2 }),
+			want:   with(func(a *Annotation) { a.Rating, a.Note, a.UpdatedAt = 5, "theirs", mergeT2 }),
+		},
+		{
+			name:   "same field, theirs newer",
+			base:   base,
+			ours:   with(func(a *Annotation) { a.Rating, a.UpdatedAt = 5, mergeT1 }),
+			theirs: with(func(a *Annotation) { a.Rating, a.UpdatedAt = 1, mergeT2 }),
+			want:   with(func(a *Annotation) { a.Rating, a.UpdatedAt = 1, mergeT2 }),
+		},
+		{
+			name:   "same field, ours newer",
+			base:   base,
+			ours:   with(func(a *Annotation) { a.Note, a.UpdatedAt = "ours", mergeT2 }),
+			theirs: with(func(a *Annotation) { a.Note, a.UpdatedAt = "theirs", mergeT1 }),
+			want:   with(func(a *Annotation) { a.Note, a.UpdatedAt = "ours", mergeT2 }),
+		},
+		{
+			name:   "rating removed on one side",
+			base:   base,
+			ours:   with(func(a *Annotation) { a.Rating, a.UpdatedAt = 0, mergeT1 }),
+			theirs: base,
+			want:   with(func(a *Annotation) { a.Rating, a.UpdatedAt = 0, mergeT1 }),
+		},
+		{
+			name:   "tags of both sides",
+			base:   base,
+			ours:   with(func(a *Annotation) { a.Tags, a.UpdatedAt = []string{"a", "b"}, mergeT1 }),
+			theirs: with(func(a *Annotation) { a.Tags, a.UpdatedAt = []string{"c"}, mergeT2 }),
+			want:   with(func(a *Annotation) { a.Tags, a.UpdatedAt = []string{"b", "c"}, mergeT2 }),
+		},
+		{
+			name:   "added on both sides",
+			ours:   &Annotation{Rating: 4, Tags: []string{"a"}, UpdatedAt: mergeT1},
+			theirs: &Annotation{Note: "n", Tags: []string{"b"}, UpdatedAt: mergeT2},
+			want:   &Annotation{Rating: 4, Note: "n", Tags: []string{"a", "b"}, UpdatedAt: mergeT2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeAnnotations(tt.base, tt.ours, tt.theirs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeCollections(t *testing.T) {
+	base := &Collection{Name: "c", Description: "base", Items: quotesByID("a", "b"), CreatedAt: mergeT1, UpdatedAt: mergeT1}
+	tests := []struct {
+		name               string
+		base, ours, theirs *Collection
+		wantDescription    string
+		wantItems          string
+		wantCreated        time.Time
+		wantUpdated        time.Time
+	}{
+		{
+			name:            "one side only",
+			base:            base,
+			ours:            base,
+			theirs:          nil,
+			wantDescription: "base",
+			wantItems:       "a,b",
+			wantCreated:     mergeT1,
+			wantUpdated:     mergeT1,
+		},
+		{
+			name:            "quotes added on both sides",
+			base:            base,
+			ours:            &Collection{Name: "c", Description: "base", Items: quotesByID("a", "b", "c"), CreatedAt: mergeT1, UpdatedAt: mergeT1},
+			theirs:          &Collection{Name: "c", Description: "theirs", Items: quotesByID("b", "a", "d"), CreatedAt: mergeT1, UpdatedAt: mergeT2},
+			wantDescription: "theirs",
+			wantItems:       "b,a,d,c",
+			wantCreated:     mergeT1,
+			wantUpdated:     mergeT2,
+		},
+		{
+			name:            "description of the newer side",
+			base:            base,
+			ours:            &Collection{Name: "c", Description: "ours", Items: quotesByID("a", "b"), CreatedAt: mergeT1, UpdatedAt: mergeT2},
+			theirs:          &Collection{Name: "c", Description: "theirs", Items: quotesByID("a"), CreatedAt: mergeT1, UpdatedAt: mergeT1},
+			wantDescription: "ours",
+			wantItems:       "a",
+			wantCreated:     mergeT1,
+			wantUpdated:     mergeT2,
+		},
+		{
+			name:            "created on both sides",
+			ours:            &Collection{Name: "c", Items: quotesByID("a"), CreatedAt: mergeT1, UpdatedAt: mergeT1},
+			theirs:          &Collection{Name: "c", Items: quotesByID("b"), CreatedAt: mergeT0, UpdatedAt: mergeT0},
+			wantDescription: "",
+			wantItems:       "a,b",
+			wantCreated:     mergeT0,
+			wantUpdated:     mergeT1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeCollections(tt.base, tt.ours, tt.theirs)
+			if got.Description != tt.wantDescription {
+				t.Errorf("Description = %q, want %q", got.Description, tt.wantDescription)
+			}
+			if items := quoteIDs(got.Items); items != tt.wantItems {
+				t.Errorf("Items = %s, want %s", items, tt.wantItems)
+			}
+			if !got.CreatedAt.Equal(tt.wantCreated) || !got.UpdatedAt.Equal(tt.wantUpdated) {
+				t.Errorf("dates = %v, %v, want %v, %v", got.CreatedAt, got.UpdatedAt, tt.wantCreated, tt.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestMergeQuotes(t *testing.T) {
+	base := &Quote{ID: "q", Text: "text", Author: "A", Tags: []string{"a"}}
+	tests := []struct {
+		name               string
+		base, ours, theirs *Quote
+		want               *Quote
+	}{
+		{
+			name:   "different fields",
+			base:   base,
+			ours:   &Quote{ID: "q", Text: "longer text", Author: "A", Tags: []string{"a"}},
+			theirs: &Quote{ID: "q", Text: "text", Author: "B", Tags: []string{"a", "b"}},
+			want:   &Quote{ID: "q", Text: "longer text", Author: "B", Tags: []string{"a", "b"}},
+		},
+		{
+			name:   "same field, theirs fetched last",
+			base:   base,
+			ours:   &Quote{ID: "q", Text: "ours", Author: "A", Tags: []string{"a"}, FetchedAt: mergeT1},
+			theirs: &Quote{ID: "q", Text: "theirs", Author: "A", Tags: []string{"a"}, FetchedAt: mergeT2},
+			want:   &Quote{ID: "q", Text: "theirs", Author: "A", Tags: []string{"a"}, FetchedAt: mergeT2},
+		},
+		{
+			name:   "same field, no dates",
+			base:   base,
+			ours:   &Quote{ID: "q", Text: "ours", Author: "A", Tags: []string{"a"}},
+			theirs: &Quote{ID: "q", Text: "theirs", Author: "A", Tags: []string{"a"}},
+			want:   &Quote{ID: "q", Text: "ours", Author: "A", Tags: []string{"a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeQuotes(tt.base, tt.ours, tt.theirs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeQuotes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
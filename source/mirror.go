@@ -0,0 +1,394 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	MIRROR_DB         = "quotes.db"
+	MIRROR_STAGING    = "staging.jsonl"
+	MIRROR_CHECKPOINT = "checkpoint.json"
+
+	DEFAULT_MIRROR_PAGE_SIZE   = 100
+	DEFAULT_MIRROR_CONCURRENCY = 4
+	DEFAULT_MIRROR_INTERVAL    = 250 * time.Millisecond
+
+	// MIRROR_MAX_REMOVED is the share of the mirror a run may remove,
+	// a corpus shrinking more than that is more likely an API problem
+	MIRROR_MAX_REMOVED = 0.5
+)
+
+type MirrorOptions struct {
+	Dir         string
+	PageSize    int
+	Concurrency int
+	// Interval is the minimum delay between two requests to the source
+	Interval time.Duration
+	// Progress is called after every fetched page
+	Progress func(done, total int)
+	// AllowShrink applies a run that removes more than MIRROR_MAX_REMOVED
+	// of the mirror, such runs fail otherwise
+	AllowShrink bool
+}
+
+type MirrorDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+	Total   int
+}
+
+type mirrorCheckpoint struct {
+	PageSize   int          `json:"pageSize"`
+	TotalPages int          `json:"totalPages"`
+	Done       map[int]bool `json:"done"`
+}
+
+// Mirror copies every quote of a source into a LocalDB.
+// Interrupted runs are resumed from the last checkpoint.
+type Mirror struct {
+	source  Sources
+	options MirrorOptions
+
+	mu         sync.Mutex
+	checkpoint *mirrorCheckpoint
+	staging    *os.File
+}
+
+// NewMirror creates a Mirror object
+func NewMirror(src Sources, options MirrorOptions) *Mirror {
+	if options.PageSize <= 0 {
+		options.PageSize = DEFAULT_MIRROR_PAGE_SIZE
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = DEFAULT_MIRROR_CONCURRENCY
+	}
+	return &Mirror{
+		source:  src,
+		options: options,
+	}
+}
+
+// Run fetches all pages not yet covered by the checkpoint and, once the
+// corpus is complete, updates the database and reports what changed.
+func (m *Mirror) Run(ctx context.Context) (*MirrorDiff, error) {
+	if err := os.MkdirAll(m.options.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := m.openCheckpoint(); err != nil {
+		return nil, err
+	}
+	defer m.staging.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var tick <-chan time.Time
+	if m.options.Interval > 0 {
+		ticker := time.NewTicker(m.options.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// the first page tells how many pages there are
+	if m.checkpoint.TotalPages == 0 {
+		if err := m.fetchPage(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	pages := make(chan int)
+	errs := make(chan error, m.options.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < m.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pages {
+				if tick != nil {
+					select {
+					case <-tick:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if err := m.fetchPage(ctx, page); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	m.progress()
+feed:
+	for page := 1; page <= m.checkpoint.TotalPages; page++ {
+		if m.checkpoint.Done[page] {
+			continue
+		}
+		select {
+		case pages <- page:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pages)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.finalize()
+}
+
+func (m *Mirror) fetchPage(ctx context.Context, page int) error {
+	quotes, pag, err := m.source.Quotes(ctx, &QueryOptions{
+		Page:  int32(page),
+		Limit: int32(m.options.PageSize),
+	})
+	if err != nil {
+		return fmt.Errorf("could not fetch page %d: %v", page, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	enc := json.NewEncoder(m.staging)
+	for _, q := range quotes {
+		if err := enc.Encode(q); err != nil {
+			return err
+		}
+	}
+	if err := m.staging.Sync(); err != nil {
+		return err
+	}
+	if pag != nil && pag.TotalPages > m.checkpoint.TotalPages {
+		m.checkpoint.TotalPages = pag.TotalPages
+	}
+	m.checkpoint.Done[page] = true
+	if err := writeJSONFile(filepath.Join(m.options.Dir, MIRROR_CHECKPOINT), m.checkpoint); err != nil {
+		return err
+	}
+	m.progressLocked()
+	return nil
+}
+
+func (m *Mirror) progress() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progressLocked()
+}
+
+func (m *Mirror) progressLocked() {
+	if m.options.Progress != nil {
+		m.options.Progress(len(m.checkpoint.Done), m.checkpoint.TotalPages)
+	}
+}
+
+func (m *Mirror) openCheckpoint() error {
+	cpPath := filepath.Join(m.options.Dir, MIRROR_CHECKPOINT)
+	stPath := filepath.Join(m.options.Dir, MIRROR_STAGING)
+
+	cp := &mirrorCheckpoint{}
+	err := readJSONFile(cpPath, cp)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read checkpoint: %v", err)
+	}
+	// a checkpoint made with another page size can not be resumed
+	if err != nil || cp.PageSize != m.options.PageSize || cp.Done == nil {
+		cp = &mirrorCheckpoint{PageSize: m.options.PageSize, Done: map[int]bool{}}
+		if err := os.Remove(stPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	m.checkpoint = cp
+
+	// pages are staged again after a record cut by a kill or a full disk
+	if err := dropPartialLine(stPath); err != nil {
+		return fmt.Errorf("could not repair %s: %v", stPath, err)
+	}
+	staging, err := os.OpenFile(stPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	m.staging = staging
+	return nil
+}
+
+func (m *Mirror) finalize() (*MirrorDiff, error) {
+	stPath := filepath.Join(m.options.Dir, MIRROR_STAGING)
+	current, err := readQuotesFile(stPath)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := OpenLocalDB(filepath.Join(m.options.Dir, MIRROR_DB))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ids := db.IDs()
+	removed := 0
+	for _, id := range ids {
+		if _, ok := current[id]; !ok {
+			removed++
+		}
+	}
+	if !m.options.AllowShrink && removed > 0 && float64(removed) > MIRROR_MAX_REMOVED*float64(len(ids)) {
+		return nil, fmt.Errorf("the source returned %d quotes, %d of the %d mirrored would be removed; run again with -allow-shrink if this is expected",
+			len(current), removed, len(ids))
+	}
+
+	diff := &MirrorDiff{Total: len(current)}
+	for id, q := range current {
+		old, err := db.Get(id)
+		switch {
+		case err == ErrQuoteNotFound:
+			diff.Added = append(diff.Added, id)
+		case err != nil:
+			return nil, err
+		case old.Equal(q):
+			continue
+		default:
+			diff.Changed = append(diff.Changed, id)
+		}
+		if err := db.Put(q); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range ids {
+		if _, ok := current[id]; ok {
+			continue
+		}
+		diff.Removed = append(diff.Removed, id)
+		if err := db.Delete(id); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Compact(); err != nil {
+		return nil, err
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	m.staging.Close()
+	if err := os.Remove(stPath); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(filepath.Join(m.options.Dir, MIRROR_CHECKPOINT)); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// dropPartialLine truncates a JSON lines file after its last complete line
+func dropPartialLine(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	// read backwards for the last newline, the file can be large
+	buf := make([]byte, 4096)
+	end := stat.Size()
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			end = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+	if end == stat.Size() {
+		return nil
+	}
+	return f.Truncate(end)
+}
+
+// readQuotesFile loads a JSON lines file of quotes indexed by ID
+func readQuotesFile(path string) (map[string]*Quote, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	quotes := map[string]*Quote{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		q := &Quote{}
+		if err := json.Unmarshal(scanner.Bytes(), q); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		quotes[q.ID] = q
+	}
+	return quotes, scanner.Err()
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	// a temporary file of its own, writers may race for path
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -3,17 +3,47 @@ package source
 import (
 	"fmt"
 	"net/url"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/pterm/pterm"
 )
 
 type QueryOptions struct {
-	Author string
-	Genre  string
-	Query  string
-	Page   int32
-	Limit  int32
+	Author string `json:"author,omitempty"`
+	Genre  string `json:"genre,omitempty"`
+	Query  string `json:"query,omitempty"`
+	Page   int32  `json:"page,omitempty"`
+	Limit  int32  `json:"limit,omitempty"`
+	// Sort is one of SortKeys, empty for the source order
+	Sort string `json:"sort,omitempty"`
+	// Seed fixes the order of the random sort
+	Seed int64 `json:"seed,omitempty"`
+	// SeedPicked marks a Seed picked for this run rather than asked for,
+	// it is not kept when the search is stored
+	SeedPicked bool `json:"-"`
+	// length constraints, 0 for none
+	MinChars int `json:"minChars,omitempty"`
+	MaxChars int `json:"maxChars,omitempty"`
+	MinWords int `json:"minWords,omitempty"`
+	MaxWords int `json:"maxWords,omitempty"`
+	// MaxLines counts the lines once wrapped at LINE_WIDTH
+	MaxLines int `json:"maxLines,omitempty"`
+	// FitsIn limits the length of PostText, e.g. 280 for a post
+	FitsIn int `json:"fitsIn,omitempty"`
+	// Genres and Authors add to Genre and Author: a quote matches any of
+	// the genres and any of the authors
+	Genres  []string `json:"genres,omitempty"`
+	Authors []string `json:"authors,omitempty"`
+	// quotes of these genres or by these authors are left out
+	ExcludeGenres  []string `json:"excludeGenres,omitempty"`
+	ExcludeAuthors []string `json:"excludeAuthors,omitempty"`
+	// filters on the annotations, applied by Filtered, see ParseFilter
+	MinRating int      `json:"minRating,omitempty"`
+	MaxRating int      `json:"maxRating,omitempty"`
+	Tagged    []string `json:"tagged,omitempty"`
+	NoteQuery string   `json:"noteQuery,omitempty"`
 }
 
 func (qgp *QueryOptions) Sprint() string {
@@ -37,16 +67,88 @@ func (qgp *QueryOptions) Sprint() string {
 }
 
 type Quote struct {
-	ID     string
-	Text   string
-	Author string
-	Genre  string
+	ID     string `json:"id"`
+	Text   string `json:"text"`
+	Author string `json:"author"`
+	Genre  string `json:"genre"`
+	// Tags holds every genre of the quote, Genre being the main one
+	Tags     []string `json:"tags,omitempty"`
+	Work     string   `json:"work,omitempty"`
+	Year     int      `json:"year,omitempty"`
+	Language string   `json:"language,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	// provenance
+	Provider string `json:"provider,omitempty"`
+	// FetchedAt is zero for quotes not fetched from a remote source, it
+	// is always written as omitempty does not apply to structs
+	FetchedAt time.Time `json:"fetchedAt"`
+	// ServedBy names the fallback that served the quote in place of the
+	// requested source, it is never stored
+	ServedBy string `json:"-"`
+	// Annotation is attached from the user's Annotations, it is stored there
+	Annotation *Annotation `json:"-"`
 }
 
 func (q *Quote) Sprint() string {
-	return fmt.Sprintf("%s \n---------------\n%s \n-- %s", strings.ToUpper(q.Genre),
+	s := fmt.Sprintf("%s \n---------------\n%s \n-- %s", strings.ToUpper(strings.Join(q.Genres(), ", ")),
 		pterm.DefaultParagraph.WithMaxWidth(60).Sprintln(q.Text),
-		q.Author)
+		q.Attribution())
+	if q.Annotation != nil && !q.Annotation.IsEmpty() {
+		s += "\n" + q.Annotation.Sprint()
+	}
+	if q.ServedBy != "" {
+		s += pterm.FgGray.Sprintf("\n(served by %s)", q.ServedBy)
+	}
+	return s
+}
+
+// Attribution formats the author followed by the work and year when known
+func (q *Quote) Attribution() string {
+	var parts []string
+	if q.Author != "" {
+		parts = append(parts, q.Author)
+	}
+	if q.Work != "" {
+		parts = append(parts, q.Work)
+	}
+	attribution := strings.Join(parts, ", ")
+	if q.Year != 0 {
+		attribution = strings.TrimSpace(fmt.Sprintf("%s (%d)", attribution, q.Year))
+	}
+	return attribution
+}
+
+// Genres returns the main genre followed by the other tags
+func (q *Quote) Genres() []string {
+	var genres []string
+	if q.Genre != "" {
+		genres = append(genres, q.Genre)
+	}
+	for _, t := range q.Tags {
+		if t != "" && !strings.EqualFold(t, q.Genre) {
+			genres = append(genres, t)
+		}
+	}
+	return genres
+}
+
+// HasGenre checks the main genre and the tags, ignoring case
+func (q *Quote) HasGenre(genre string) bool {
+	for _, g := range q.Genres() {
+		if strings.EqualFold(g, genre) {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal compares the content of two quotes, ignoring when and how they were fetched
+func (q *Quote) Equal(other *Quote) bool {
+	a, b := *q, *other
+	a.FetchedAt, b.FetchedAt = time.Time{}, time.Time{}
+	a.ServedBy, b.ServedBy = "", ""
+	a.Annotation, b.Annotation = nil, nil
+	return reflect.DeepEqual(a, b)
 }
 
 func (q *Quote) Print() {
@@ -66,3 +168,30 @@ func (q *Quote) header() pterm.HeaderPrinter {
 		Margin:          1,
 	}
 }
+
+// PrintQuotesPage renders quotes as a grid of panels
+func PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	panels := make(pterm.Panels, 100)
+
+	row := 0
+	col := 0
+	panels[row] = make([]pterm.Panel, columns)
+	for _, q := range quotes {
+		// p := pterm.DefaultBox.Sprint(q.Sprint())
+		p := q.HSprint()
+		panel := pterm.Panel{Data: p}
+
+		panels[row][col] = panel
+		col += 1
+		if col >= columns {
+			row += 1
+			col = 0
+			panels[row] = make([]pterm.Panel, columns)
+		}
+	}
+
+	// pRender, _ := pterm.DefaultPanel.WithPanels(panels).Srender()
+	// pterm.DefaultBox.WithTitle(title).WithTitleBottomRight().Println(pRender)
+	pterm.DefaultPanel.WithPanels(panels).WithBottomPadding(1).WithPadding(1).WithSameColumnWidth().Render()
+	pterm.DefaultHeader.Println(title)
+}
@@ -4,130 +4,210 @@ package source
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pterm/pterm"
 )
 
 const (
-	QUOTEGARDEN_URI = "https://quote-garden.herokuapp.com/api/v3"
+	QUOTEGARDEN_URI      = "https://quote-garden.herokuapp.com/api/v3"
+	QUOTEGARDEN_PROVIDER = "QuoteGarden"
+	// the QuoteGarden corpus is english only
+	QUOTEGARDEN_LANGUAGE = "en"
 )
 
+// qgVersionPath matches a BaseURL that already names the API version,
+// any other BaseURL is a server root the version is negotiated for
+var qgVersionPath = regexp.MustCompile(`/api/(v\d+)$`)
+
 type QuoteGarden struct {
-	BaseURL    string
+	BaseURL string
+	// Version selects the schema adapter, empty to negotiate it
+	Version    string
 	HTTPClient *http.Client
+	// Silent disables the request spinner, for non-interactive use
+	Silent bool
+	// Strict rejects responses with fields the models do not know
+	Strict bool
+
+	mu      sync.Mutex
+	adapter QGAdapter
+	api     string
 }
 
 func NewQuoteGarden() *QuoteGarden {
+	// the default options cannot fail
+	client, _ := NewHTTPClient(HTTPOptions{})
 	return &QuoteGarden{
-		BaseURL: QUOTEGARDEN_URI,
-		HTTPClient: &http.Client{
-			Timeout: time.Minute,
-		},
+		BaseURL:    QUOTEGARDEN_URI,
+		HTTPClient: client,
 	}
 }
 
 func (qg *QuoteGarden) RandomQuote(ctx context.Context) (*Quote, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/quotes/random", qg.BaseURL), nil)
+	adapter, api, err := qg.negotiate(ctx)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-
-	res := &QGQuotes{}
-	if err := qg.sendRequest(req, res); err != nil {
-		return nil, err
-	}
-	return res.Data[0].ToQuote(), nil
+	return adapter.RandomQuote(ctx, qg, api)
 }
 
 func (qg *QuoteGarden) AllGenres(ctx context.Context) ([]string, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/genres", qg.BaseURL), nil)
+	adapter, api, err := qg.negotiate(ctx)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-
-	res := &QGGenres{}
-	if err := qg.sendRequest(req, res); err != nil {
-		return nil, err
-	}
-	return res.Data, nil
+	return adapter.AllGenres(ctx, qg, api)
 }
 
 func (qg *QuoteGarden) AllAuthors(ctx context.Context) ([]string, error) {
-	limit := 100
-	page := 1
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/authors?limit=%d&page=%d", qg.BaseURL, limit, page), nil)
+	adapter, api, err := qg.negotiate(ctx)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-
-	res := &QGAuthors{}
-	if err := qg.sendRequest(req, res); err != nil {
-		return nil, err
-	}
-	return res.Data, nil
+	return adapter.AllAuthors(ctx, qg, api)
 }
 
 func (qg *QuoteGarden) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
-	req, err := http.NewRequest("GET",
-		fmt.Sprintf("%s/quotes?%s", qg.BaseURL, options.Sprint()),
-		nil)
+	adapter, api, err := qg.negotiate(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	req = req.WithContext(ctx)
-	res := &QGQuotes{}
-	if err := qg.sendRequest(req, res); err != nil {
-		return nil, nil, err
+	return adapter.Quotes(ctx, qg, api, options)
+}
+
+// APIVersion returns the schema version spoken with the server
+func (qg *QuoteGarden) APIVersion(ctx context.Context) (string, error) {
+	adapter, _, err := qg.negotiate(ctx)
+	if err != nil {
+		return "", err
 	}
-	return res.DataToQuotes(), &res.Pagination, nil
+	return adapter.Version(), nil
 }
 
-func (qg *QuoteGarden) PrintQuotesPage(title string, quotes []*Quote, columns int) {
-	panels := make(pterm.Panels, 100)
-
-	row := 0
-	col := 0
-	panels[row] = make([]pterm.Panel, columns)
-	for _, q := range quotes {
-		// p := pterm.DefaultBox.Sprint(q.Sprint())
-		p := q.HSprint()
-		panel := pterm.Panel{Data: p}
-
-		panels[row][col] = panel
-		col += 1
-		if col >= columns {
-			row += 1
-			col = 0
-			panels[row] = make([]pterm.Panel, columns)
+// negotiate picks the adapter once: the configured Version, the version in
+// BaseURL or else the first schema the server answers to. A configured
+// Version must agree with a version pinned in BaseURL.
+func (qg *QuoteGarden) negotiate(ctx context.Context) (QGAdapter, string, error) {
+	qg.mu.Lock()
+	if qg.adapter != nil {
+		defer qg.mu.Unlock()
+		return qg.adapter, qg.api, nil
+	}
+	qg.mu.Unlock()
+
+	base := strings.TrimRight(qg.BaseURL, "/")
+	version := qg.Version
+	pinned := qgVersionPath.MatchString(base)
+	if m := qgVersionPath.FindStringSubmatch(base); m != nil && version == "" {
+		if _, ok := QGAdapters[m[1]]; ok {
+			version = m[1]
+		}
+	}
+	apiFor := func(adapter QGAdapter) string {
+		if pinned {
+			return base
+		}
+		return base + adapter.Prefix()
+	}
+
+	if version != "" {
+		adapter, ok := QGAdapters[version]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown QuoteGarden API version %q", version)
 		}
+		// a pinned path and a configured version must name the same API
+		if pinned && !strings.HasSuffix(base, adapter.Prefix()) {
+			return nil, "", fmt.Errorf("QuoteGarden URL %s names another API than version %s, drop the version or the /api path", base, version)
+		}
+		return qg.use(adapter, apiFor(adapter))
+	}
+
+	// newer schemas first, the genre list is the cheapest request. The
+	// probes run unlocked, concurrent requests may probe as well.
+	var problems []string
+	for _, v := range QGProbeOrder {
+		adapter := QGAdapters[v]
+		api := apiFor(adapter)
+		if _, err := adapter.AllGenres(ctx, qg, api); err != nil {
+			if !isSchemaError(err) {
+				// the server is down or not reachable, no other version
+				// will do better
+				return nil, "", err
+			}
+			problems = append(problems, fmt.Sprintf("%s: %v", v, err))
+			continue
+		}
+		return qg.use(adapter, api)
+	}
+	return nil, "", fmt.Errorf("no supported QuoteGarden API at %s (%s)", base, strings.Join(problems, "; "))
+}
+
+// use keeps the negotiated adapter, or the one a concurrent request
+// negotiated first
+func (qg *QuoteGarden) use(adapter QGAdapter, api string) (QGAdapter, string, error) {
+	qg.mu.Lock()
+	defer qg.mu.Unlock()
+	if qg.adapter == nil {
+		qg.adapter, qg.api = adapter, api
+	}
+	return qg.adapter, qg.api, nil
+}
+
+// isSchemaError tells whether err means the server does not speak the
+// probed version: a missing route or a response that does not decode.
+// Server errors and rate limits are not, the server may answer later.
+func isSchemaError(err error) bool {
+	var statusErr *StatusError
+	var responseErr *ResponseError
+	switch {
+	case err == ErrEmptyData || errors.As(err, &responseErr):
+		return true
+	case errors.As(err, &statusErr):
+		// a status below 400 comes from an envelope that does not match
+		return statusErr.HTTPStatus == http.StatusNotFound || statusErr.HTTPStatus == http.StatusGone ||
+			statusErr.HTTPStatus < http.StatusBadRequest
+	}
+	return false
+}
+
+// get requests a JSON document and decodes it into v
+func (qg *QuoteGarden) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
 	}
+	return qg.sendRequest(req.WithContext(ctx), v)
+}
 
-	// pRender, _ := pterm.DefaultPanel.WithPanels(panels).Srender()
-	// pterm.DefaultBox.WithTitle(title).WithTitleBottomRight().Println(pRender)
-	pterm.DefaultPanel.WithPanels(panels).WithBottomPadding(1).WithPadding(1).WithSameColumnWidth().Render()
-	pterm.DefaultHeader.Println(title)
+func (qg *QuoteGarden) PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	PrintQuotesPage(title, quotes, columns)
 }
 
 func (qg *QuoteGarden) sendRequest(req *http.Request, v interface{}) (retErr error) {
-	spinnerSuccess, _ := pterm.DefaultSpinner.Start("Sending request...")
-	defer func() {
-		if retErr != nil {
-			spinnerSuccess.Fail()
-			return
-		}
-		spinnerSuccess.Success()
-	}()
+	if !qg.Silent && !isQuiet(req.Context()) {
+		const text = "Sending request..."
+		spinnerSuccess, _ := pterm.DefaultSpinner.Start(text)
+		defer func() {
+			if retErr != nil {
+				spinnerSuccess.Fail(text)
+				return
+			}
+			spinnerSuccess.Success(text)
+		}()
+		req = req.WithContext(WithQueuedFeedback(req.Context(), func(wait time.Duration) {
+			spinnerSuccess.UpdateText(queuedText(wait))
+		}))
+	}
 
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Set("Accept", "application/json; charset=utf-8")
-	// req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 
 	res, err := qg.HTTPClient.Do(req)
 	if err != nil {
@@ -137,12 +217,24 @@ func (qg *QuoteGarden) sendRequest(req *http.Request, v interface{}) (retErr err
 	defer res.Body.Close()
 
 	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("unknown error, status code: %d", res.StatusCode)
+		// the API describes most errors in its envelope
+		envelope := &QGEnvelope{}
+		if json.NewDecoder(res.Body).Decode(envelope) == nil && envelope.Message != "" {
+			return &StatusError{HTTPStatus: res.StatusCode, StatusCode: envelope.StatusCode, Message: envelope.Message}
+		}
+		return &StatusError{HTTPStatus: res.StatusCode}
 	}
 
-	if retErr = json.NewDecoder(res.Body).Decode(&v); retErr != nil {
-		return retErr
+	dec := json.NewDecoder(res.Body)
+	if qg.Strict {
+		dec.DisallowUnknownFields()
+	}
+	if retErr = dec.Decode(v); retErr != nil {
+		return &ResponseError{Problem: fmt.Sprintf("could not be decoded: %v", retErr)}
 	}
 
+	if env, ok := v.(qgResponse); ok {
+		return env.validate(res.StatusCode, qg.Strict)
+	}
 	return nil
 }
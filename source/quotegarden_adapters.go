@@ -0,0 +1,88 @@
+package source
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	QG_V2 = "v2"
+	QG_V3 = "v3"
+	// QG_COMPAT speaks the v3 routes but accepts renamed envelope
+	// and quote fields, as found on self-hosted forks
+	QG_COMPAT = "compat"
+)
+
+// QGAdapter maps one QuoteGarden schema version onto Quote
+type QGAdapter interface {
+	Version() string
+	// Prefix is the API path below the server root
+	Prefix() string
+	RandomQuote(ctx context.Context, qg *QuoteGarden, api string) (*Quote, error)
+	AllGenres(ctx context.Context, qg *QuoteGarden, api string) ([]string, error)
+	AllAuthors(ctx context.Context, qg *QuoteGarden, api string) ([]string, error)
+	Quotes(ctx context.Context, qg *QuoteGarden, api string, options *QueryOptions) ([]*Quote, *Pagination, error)
+}
+
+// QGAdapters holds the known schema versions by name
+var QGAdapters = map[string]QGAdapter{
+	QG_V2:     qgV2{},
+	QG_V3:     qgV3{},
+	QG_COMPAT: qgCompat{},
+}
+
+// QGProbeOrder is the order versions are tried in when none is configured
+var QGProbeOrder = []string{QG_V3, QG_V2, QG_COMPAT}
+
+// qgV3 is the schema QUOTEGARDEN_URI serves
+type qgV3 struct{}
+
+func (qgV3) Version() string { return QG_V3 }
+
+func (qgV3) Prefix() string { return "/api/v3" }
+
+func (qgV3) RandomQuote(ctx context.Context, qg *QuoteGarden, api string) (*Quote, error) {
+	res := &QGQuotes{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/quotes/random", api), res); err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 {
+		return nil, ErrEmptyData
+	}
+	return res.Data[0].ToQuote(), nil
+}
+
+func (qgV3) AllGenres(ctx context.Context, qg *QuoteGarden, api string) ([]string, error) {
+	res := &QGGenres{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/genres", api), res); err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 {
+		return nil, ErrEmptyData
+	}
+	return res.Data, nil
+}
+
+func (qgV3) AllAuthors(ctx context.Context, qg *QuoteGarden, api string) ([]string, error) {
+	limit := 100
+	page := 1
+
+	res := &QGAuthors{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/authors?limit=%d&page=%d", api, limit, page), res); err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 {
+		return nil, ErrEmptyData
+	}
+	return res.Data, nil
+}
+
+func (qgV3) Quotes(ctx context.Context, qg *QuoteGarden, api string, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	res := &QGQuotes{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/quotes?%s", api, options.Sprint()), res); err != nil {
+		return nil, nil, err
+	}
+	pagination := res.Pagination
+	pagination.TotalQuotes = res.TotalQuotes
+	return res.DataToQuotes(), &pagination, nil
+}
@@ -0,0 +1,156 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Field names tried in order by the compat adapter; the first one
+// present in a response is used.
+var (
+	qgCompatStatusKeys = []string{"statusCode", "status", "code"}
+	qgCompatDataKeys   = []string{"data", "quotes", "results", "items", "genres", "authors"}
+	qgCompatQuoteKeys  = []string{"quote", "data", "result"}
+	qgCompatIDKeys     = []string{"_id", "id", "uuid"}
+	qgCompatTextKeys   = []string{"quoteText", "text", "quote", "content", "body"}
+	qgCompatAuthorKeys = []string{"quoteAuthor", "author", "authorName"}
+	qgCompatGenreKeys  = []string{"quoteGenre", "genre", "category", "tag"}
+)
+
+// qgLoose is a response decoded without a fixed schema
+type qgLoose map[string]json.RawMessage
+
+// lookup decodes the first of keys that is present into v
+func (r qgLoose) lookup(v interface{}, keys ...string) (string, bool) {
+	for _, key := range keys {
+		raw, ok := r[key]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		if json.Unmarshal(raw, v) == nil {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func (r qgLoose) validate(httpStatus int, strict bool) error {
+	env := &QGEnvelope{}
+	r.lookup(&env.StatusCode, qgCompatStatusKeys...)
+	r.lookup(&env.Message, "message", "error")
+	return env.validate(httpStatus, strict)
+}
+
+func (r qgLoose) pagination() *Pagination {
+	pagination := &Pagination{}
+	if _, ok := r.lookup(pagination, "pagination", "meta"); !ok {
+		r.lookup(&pagination.CurrentPage, "currentPage", "page")
+		r.lookup(&pagination.TotalPages, "totalPages", "pages")
+		r.lookup(&pagination.NextPage, "nextPage")
+	}
+	if pagination.TotalQuotes == 0 {
+		r.lookup(&pagination.TotalQuotes, "totalQuotes", "total", "count")
+	}
+	if pagination.NextPage == 0 && pagination.CurrentPage < pagination.TotalPages {
+		pagination.NextPage = pagination.CurrentPage + 1
+	}
+	return pagination
+}
+
+func (r qgLoose) quotes(strict bool) ([]*QGQuote, error) {
+	var items []qgLoose
+	key, ok := r.lookup(&items, qgCompatDataKeys...)
+	if !ok {
+		return nil, &ResponseError{Field: "data", Problem: "is missing"}
+	}
+	quotes := make([]*QGQuote, 0, len(items))
+	for _, item := range items {
+		quotes = append(quotes, item.quote())
+	}
+	return validQGQuotes(key, quotes, strict)
+}
+
+func (r qgLoose) names() ([]string, error) {
+	var names []string
+	key, ok := r.lookup(&names, qgCompatDataKeys...)
+	if !ok {
+		return nil, &ResponseError{Field: "data", Problem: "is missing"}
+	}
+	if len(names) == 0 {
+		return nil, ErrEmptyData
+	}
+	return names, validateNames(key, names)
+}
+
+func (r qgLoose) quote() *QGQuote {
+	q := &QGQuote{}
+	r.lookup(&q.ID, qgCompatIDKeys...)
+	r.lookup(&q.QuoteText, qgCompatTextKeys...)
+	r.lookup(&q.QuoteAuthor, qgCompatAuthorKeys...)
+	r.lookup(&q.QuoteGenre, qgCompatGenreKeys...)
+	return q
+}
+
+// qgCompat follows the v3 routes with a tolerant decoder
+type qgCompat struct{}
+
+func (qgCompat) Version() string { return QG_COMPAT }
+
+func (qgCompat) Prefix() string { return "/api/v3" }
+
+func (qgCompat) RandomQuote(ctx context.Context, qg *QuoteGarden, api string) (*Quote, error) {
+	res := qgLoose{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/quotes/random", api), &res); err != nil {
+		return nil, err
+	}
+	// a single object, or a list holding it
+	var single qgLoose
+	if key, ok := res.lookup(&single, qgCompatQuoteKeys...); ok {
+		q := single.quote()
+		if err := validateQGQuotes(key, []*QGQuote{q}); err != nil {
+			return nil, err
+		}
+		return q.ToQuote(), nil
+	}
+	quotes, err := res.quotes(qg.Strict)
+	if err != nil {
+		return nil, err
+	}
+	if len(quotes) == 0 {
+		return nil, ErrEmptyData
+	}
+	return quotes[0].ToQuote(), nil
+}
+
+func (qgCompat) AllGenres(ctx context.Context, qg *QuoteGarden, api string) ([]string, error) {
+	res := qgLoose{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/genres", api), &res); err != nil {
+		return nil, err
+	}
+	return res.names()
+}
+
+func (qgCompat) AllAuthors(ctx context.Context, qg *QuoteGarden, api string) ([]string, error) {
+	res := qgLoose{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/authors?limit=%d&page=%d", api, 100, 1), &res); err != nil {
+		return nil, err
+	}
+	return res.names()
+}
+
+func (qgCompat) Quotes(ctx context.Context, qg *QuoteGarden, api string, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	res := qgLoose{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/quotes?%s", api, options.Sprint()), &res); err != nil {
+		return nil, nil, err
+	}
+	data, err := res.quotes(qg.Strict)
+	if err != nil {
+		return nil, nil, err
+	}
+	quotes := make([]*Quote, 0, len(data))
+	for _, q := range data {
+		quotes = append(quotes, q.ToQuote())
+	}
+	return quotes, res.pagination(), nil
+}
@@ -0,0 +1,43 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrEmptyData is returned when QuoteGarden answers without the data asked for
+var ErrEmptyData = errors.New("QuoteGarden response has no data")
+
+// StatusError reports a failed request or an envelope disagreeing with HTTP
+type StatusError struct {
+	HTTPStatus int
+	// StatusCode and Message come from the response envelope
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	switch {
+	case e.Message != "" && e.StatusCode != 0 && e.StatusCode != e.HTTPStatus:
+		return fmt.Sprintf("QuoteGarden status code %d does not match HTTP status %d: %s", e.StatusCode, e.HTTPStatus, e.Message)
+	case e.Message != "":
+		return fmt.Sprintf("QuoteGarden error, status code: %d: %s", e.HTTPStatus, e.Message)
+	case e.StatusCode != 0 && e.StatusCode != e.HTTPStatus:
+		return fmt.Sprintf("QuoteGarden status code %d does not match HTTP status %d", e.StatusCode, e.HTTPStatus)
+	}
+	return fmt.Sprintf("unknown error, status code: %d", e.HTTPStatus)
+}
+
+// ResponseError reports a response that does not match the expected schema
+type ResponseError struct {
+	// Field is the JSON path of the offending field, empty for the whole body
+	Field   string
+	Problem string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid QuoteGarden response: %s", e.Problem)
+	}
+	return fmt.Sprintf("invalid QuoteGarden response: %s %s", e.Field, e.Problem)
+}
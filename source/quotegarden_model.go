@@ -1,27 +1,119 @@
 package source
 
-type QGGenres struct {
+import (
+	"fmt"
+	"time"
+)
+
+// QGEnvelope holds the fields shared by every QuoteGarden response
+type QGEnvelope struct {
 	StatusCode  int        `json:"statusCode"`
 	Message     string     `json:"message"`
 	Pagination  Pagination `json:"pagination"`
 	TotalQuotes int        `json:"totalQuotes"`
-	Data        []string   `json:"data"`
+}
+
+type QGGenres struct {
+	QGEnvelope
+	Data []string `json:"data"`
 }
 
 type QGAuthors struct {
-	StatusCode  int        `json:"statusCode"`
-	Message     string     `json:"message"`
-	Pagination  Pagination `json:"pagination"`
-	TotalQuotes int        `json:"totalQuotes"`
-	Data        []string   `json:"data"`
+	QGEnvelope
+	Data []string `json:"data"`
 }
 
 type QGQuotes struct {
-	StatusCode  int        `json:"statusCode"`
-	Message     string     `json:"message"`
-	Pagination  Pagination `json:"pagination"`
-	TotalQuotes int        `json:"totalQuotes"`
-	Data        []*QGQuote `json:"data"`
+	QGEnvelope
+	Data []*QGQuote `json:"data"`
+}
+
+// qgResponse is implemented by the responses sendRequest can check
+type qgResponse interface {
+	validate(httpStatus int, strict bool) error
+}
+
+func (env *QGEnvelope) validate(httpStatus int, strict bool) error {
+	if env.StatusCode != 0 && env.StatusCode != httpStatus {
+		return &StatusError{HTTPStatus: httpStatus, StatusCode: env.StatusCode, Message: env.Message}
+	}
+	return nil
+}
+
+func (qgg *QGGenres) validate(httpStatus int, strict bool) error {
+	if err := qgg.QGEnvelope.validate(httpStatus, strict); err != nil {
+		return err
+	}
+	return validateNames("data", qgg.Data)
+}
+
+func (qga *QGAuthors) validate(httpStatus int, strict bool) error {
+	if err := qga.QGEnvelope.validate(httpStatus, strict); err != nil {
+		return err
+	}
+	return validateNames("data", qga.Data)
+}
+
+func (qgq *QGQuotes) validate(httpStatus int, strict bool) error {
+	if err := qgq.QGEnvelope.validate(httpStatus, strict); err != nil {
+		return err
+	}
+	data, err := validQGQuotes("data", qgq.Data, strict)
+	if err != nil {
+		return err
+	}
+	qgq.Data = data
+	return nil
+}
+
+// validQGQuotes drops the records of a page that cannot be shown or
+// stored, so one bad upstream record does not fail the whole page.
+// A missing author is kept, the quote is shown without attribution.
+// A page without a single valid record means the schema changed and
+// is an error, as is any bad record in strict mode.
+func validQGQuotes(name string, quotes []*QGQuote, strict bool) ([]*QGQuote, error) {
+	if strict {
+		if err := validateQGQuotes(name, quotes); err != nil {
+			return nil, err
+		}
+		return quotes, nil
+	}
+	valid := make([]*QGQuote, 0, len(quotes))
+	for _, q := range quotes {
+		if q != nil && q.ID != "" && q.QuoteText != "" {
+			valid = append(valid, q)
+		}
+	}
+	if len(quotes) > 0 && len(valid) == 0 {
+		return nil, validateQGQuotes(name, quotes)
+	}
+	return valid, nil
+}
+
+// validateQGQuotes checks the fields every schema version requires,
+// for responses made of a single quote
+func validateQGQuotes(name string, quotes []*QGQuote) error {
+	for i, q := range quotes {
+		field := fmt.Sprintf("%s[%d]", name, i)
+		switch {
+		case q == nil:
+			return &ResponseError{Field: field, Problem: "is null"}
+		case q.ID == "":
+			return &ResponseError{Field: field + "._id", Problem: "is missing"}
+		case q.QuoteText == "":
+			return &ResponseError{Field: field + ".quoteText", Problem: "is missing"}
+		}
+	}
+	return nil
+}
+
+func validateNames(name string, names []string) error {
+	for i, n := range names {
+		if n == "" {
+			return &ResponseError{Field: fmt.Sprintf("%s[%d]", name, i), Problem: "is empty"}
+		}
+	}
+	return nil
 }
 
 func (qgq *QGQuotes) DataToQuotes() (retQ []*Quote) {
@@ -35,6 +127,11 @@ type Pagination struct {
 	CurrentPage int `json:"currentPage"`
 	NextPage    int `json:"nextPage"`
 	TotalPages  int `json:"totalPages"`
+	// TotalQuotes counts every result, 0 when the source cannot tell
+	TotalQuotes int `json:"totalQuotes,omitempty"`
+	// Truncated tells that the source stopped reading before the end of
+	// its results, the totals and orders only cover what it read
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 type QGQuote struct {
@@ -42,13 +139,22 @@ type QGQuote struct {
 	QuoteText   string `json:"quoteText"`
 	QuoteAuthor string `json:"quoteAuthor"`
 	QuoteGenre  string `json:"quoteGenre"`
+	// document version added by the API's database
+	Version int `json:"__v"`
 }
 
 func (qgq *QGQuote) ToQuote() *Quote {
-	return &Quote{
-		ID:     qgq.ID,
-		Author: qgq.QuoteAuthor,
-		Text:   qgq.QuoteText,
-		Genre:  qgq.QuoteGenre,
+	q := &Quote{
+		ID:        qgq.ID,
+		Author:    qgq.QuoteAuthor,
+		Text:      qgq.QuoteText,
+		Genre:     qgq.QuoteGenre,
+		Language:  QUOTEGARDEN_LANGUAGE,
+		Provider:  QUOTEGARDEN_PROVIDER,
+		FetchedAt: time.Now(),
+	}
+	if qgq.QuoteGenre != "" {
+		q.Tags = []string{qgq.QuoteGenre}
 	}
+	return q
 }
@@ -0,0 +1,147 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// QG2Envelope holds the fields shared by every v2 response
+type QG2Envelope struct {
+	StatusCode int    `json:"statusCode"`
+	Message    string `json:"message"`
+}
+
+type QG2Random struct {
+	QG2Envelope
+	Quote *QGQuote `json:"quote"`
+}
+
+type QG2Genres struct {
+	QG2Envelope
+	Genres []string `json:"genres"`
+}
+
+type QG2Authors struct {
+	QG2Envelope
+	Authors []string `json:"authors"`
+}
+
+// QG2Quotes is the page returned by the v2 search, genre and author routes
+type QG2Quotes struct {
+	QG2Envelope
+	CurrentPage int        `json:"currentPage"`
+	TotalPages  int        `json:"totalPages"`
+	Quotes      []*QGQuote `json:"quotes"`
+}
+
+func (env *QG2Envelope) validate(httpStatus int, strict bool) error {
+	return (&QGEnvelope{StatusCode: env.StatusCode, Message: env.Message}).validate(httpStatus, strict)
+}
+
+func (r *QG2Random) validate(httpStatus int, strict bool) error {
+	if err := r.QG2Envelope.validate(httpStatus, strict); err != nil {
+		return err
+	}
+	if r.Quote == nil {
+		return ErrEmptyData
+	}
+	return validateQGQuotes("quote", []*QGQuote{r.Quote})
+}
+
+func (r *QG2Genres) validate(httpStatus int, strict bool) error {
+	if err := r.QG2Envelope.validate(httpStatus, strict); err != nil {
+		return err
+	}
+	return validateNames("genres", r.Genres)
+}
+
+func (r *QG2Authors) validate(httpStatus int, strict bool) error {
+	if err := r.QG2Envelope.validate(httpStatus, strict); err != nil {
+		return err
+	}
+	return validateNames("authors", r.Authors)
+}
+
+func (r *QG2Quotes) validate(httpStatus int, strict bool) error {
+	if err := r.QG2Envelope.validate(httpStatus, strict); err != nil {
+		return err
+	}
+	quotes, err := validQGQuotes("quotes", r.Quotes, strict)
+	if err != nil {
+		return err
+	}
+	r.Quotes = quotes
+	return nil
+}
+
+// qgV2 speaks the older API, which filters by one of author, genre
+// or search term through the path; the other filters apply to the page
+type qgV2 struct{}
+
+func (qgV2) Version() string { return QG_V2 }
+
+func (qgV2) Prefix() string { return "/api/v2" }
+
+func (qgV2) RandomQuote(ctx context.Context, qg *QuoteGarden, api string) (*Quote, error) {
+	res := &QG2Random{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/quotes/random", api), res); err != nil {
+		return nil, err
+	}
+	return res.Quote.ToQuote(), nil
+}
+
+func (qgV2) AllGenres(ctx context.Context, qg *QuoteGarden, api string) ([]string, error) {
+	res := &QG2Genres{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/genres", api), res); err != nil {
+		return nil, err
+	}
+	if len(res.Genres) == 0 {
+		return nil, ErrEmptyData
+	}
+	return res.Genres, nil
+}
+
+func (qgV2) AllAuthors(ctx context.Context, qg *QuoteGarden, api string) ([]string, error) {
+	res := &QG2Authors{}
+	if err := qg.get(ctx, fmt.Sprintf("%s/authors", api), res); err != nil {
+		return nil, err
+	}
+	if len(res.Authors) == 0 {
+		return nil, ErrEmptyData
+	}
+	return res.Authors, nil
+}
+
+func (qgV2) Quotes(ctx context.Context, qg *QuoteGarden, api string, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	rest := *options
+	route := fmt.Sprintf("%s/quotes", api)
+	switch {
+	case options.Query != "":
+		route = fmt.Sprintf("%s/quotes/%s", api, url.PathEscape(options.Query))
+		rest.Query = ""
+	case options.Author != "":
+		route = fmt.Sprintf("%s/authors/%s", api, url.PathEscape(options.Author))
+		rest.Author = ""
+	case options.Genre != "":
+		route = fmt.Sprintf("%s/genres/%s", api, url.PathEscape(options.Genre))
+		rest.Genre = ""
+	}
+	page := (&QueryOptions{Page: options.Page, Limit: options.Limit}).Sprint()
+
+	res := &QG2Quotes{}
+	if err := qg.get(ctx, fmt.Sprintf("%s?%s", route, page), res); err != nil {
+		return nil, nil, err
+	}
+	var quotes []*Quote
+	for _, q := range res.Quotes {
+		if quote := q.ToQuote(); matchesOptions(quote, &rest) {
+			quotes = append(quotes, quote)
+		}
+	}
+	pagination := &Pagination{CurrentPage: res.CurrentPage, TotalPages: res.TotalPages}
+	if res.CurrentPage < res.TotalPages {
+		pagination.NextPage = res.CurrentPage + 1
+	}
+	return quotes, pagination, nil
+}
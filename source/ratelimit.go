@@ -0,0 +1,272 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_RATE_LIMIT     = 4.0
+	DEFAULT_RATE_BURST     = 4
+	DEFAULT_MAX_CONCURRENT = 4
+
+	budgetDayLayout = "2006-01-02"
+)
+
+var ErrBudgetExhausted = errors.New("daily request budget exhausted, try again tomorrow")
+
+type RateLimitOptions struct {
+	// Rate is the sustained number of requests per second, negative for no limit
+	Rate  float64
+	Burst int
+	// MaxConcurrent caps the requests in flight, negative for no cap
+	MaxConcurrent int
+	// DailyBudget is the number of requests allowed per host and day, 0 for no budget
+	DailyBudget int
+	// BudgetFile keeps the daily counts across runs, empty to count in
+	// memory. It is only used with a DailyBudget.
+	BudgetFile string
+}
+
+type queuedFeedbackKey struct{}
+
+// WithQueuedFeedback makes the limiter call fn when a request made
+// with ctx has to wait for its turn; wait is 0 when it waits for a free slot
+func WithQueuedFeedback(ctx context.Context, fn func(wait time.Duration)) context.Context {
+	return context.WithValue(ctx, queuedFeedbackKey{}, fn)
+}
+
+func queuedText(wait time.Duration) string {
+	if wait <= 0 {
+		return "Queued behind other requests..."
+	}
+	return fmt.Sprintf("Queued by the rate limit for %s...", wait.Round(100*time.Millisecond))
+}
+
+// requestBudget is the content of the budget file
+type requestBudget struct {
+	Day   string         `json:"day"`
+	Hosts map[string]int `json:"hosts"`
+}
+
+// RateLimiter is a token bucket with a concurrency cap and a daily budget.
+// It wraps the transport of any HTTP based source.
+type RateLimiter struct {
+	options RateLimitOptions
+	slots   chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	budget requestBudget
+}
+
+// NewRateLimiter creates a RateLimiter, zero options take the defaults
+func NewRateLimiter(options RateLimitOptions) *RateLimiter {
+	if options.Rate == 0 {
+		options.Rate = DEFAULT_RATE_LIMIT
+	}
+	if options.Burst <= 0 {
+		options.Burst = DEFAULT_RATE_BURST
+	}
+	if options.MaxConcurrent == 0 {
+		options.MaxConcurrent = DEFAULT_MAX_CONCURRENT
+	}
+	if options.DailyBudget <= 0 {
+		// nothing to share with other processes, requests count in memory
+		options.BudgetFile = ""
+	}
+	l := &RateLimiter{
+		options: options,
+		tokens:  float64(options.Burst),
+		last:    time.Now(),
+	}
+	if options.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, options.MaxConcurrent)
+	}
+	return l
+}
+
+// Transport wraps next so every request goes through the limiter
+func (l *RateLimiter) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &limitedTransport{limiter: l, next: next}
+}
+
+type limitedTransport struct {
+	limiter *RateLimiter
+	next    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.Wait(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return t.next.RoundTrip(req)
+}
+
+// Wait blocks until a request to host may be sent. The returned
+// function frees the concurrency slot once the request is done.
+func (l *RateLimiter) Wait(ctx context.Context, host string) (func(), error) {
+	if err := l.spend(ctx, host); err != nil {
+		return nil, err
+	}
+
+	queued := false
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			queued = true
+			notify(ctx, 0)
+			select {
+			case l.slots <- struct{}{}:
+			case <-ctx.Done():
+				l.refund(host)
+				return nil, ctx.Err()
+			}
+		}
+	}
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	if wait := l.reserve(); wait > 0 {
+		if !queued {
+			notify(ctx, wait)
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			l.cancel()
+			l.refund(host)
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// Budget returns the daily budget per host, 0 when there is none
+func (l *RateLimiter) Budget() int {
+	return l.options.DailyBudget
+}
+
+// Used returns the requests counted today for host, by this process
+// only when there is no daily budget
+func (l *RateLimiter) Used(host string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loadBudget()
+	return l.budget.Hosts[host]
+}
+
+func notify(ctx context.Context, wait time.Duration) {
+	if fn, ok := ctx.Value(queuedFeedbackKey{}).(func(time.Duration)); ok {
+		fn(wait)
+	}
+}
+
+// reserve takes a token and returns how long to wait for it
+func (l *RateLimiter) reserve() time.Duration {
+	if l.options.Rate < 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.options.Rate
+	if l.tokens > float64(l.options.Burst) {
+		l.tokens = float64(l.options.Burst)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.options.Rate * float64(time.Second))
+}
+
+// cancel returns the token of a request that gave up waiting
+func (l *RateLimiter) cancel() {
+	if l.options.Rate < 0 {
+		return
+	}
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+}
+
+// spend counts a request against the daily budget. The budget file is
+// locked, read and written again every time, so concurrent goqu processes
+// share the budget.
+func (l *RateLimiter) spend(ctx context.Context, host string) error {
+	return l.updateBudget(ctx, func(b *requestBudget) error {
+		if l.options.DailyBudget > 0 && b.Hosts[host] >= l.options.DailyBudget {
+			return ErrBudgetExhausted
+		}
+		b.Hosts[host]++
+		return nil
+	})
+}
+
+// refund gives back the budget of a request that was never sent
+func (l *RateLimiter) refund(host string) {
+	// the request was cancelled, its context is done already
+	l.updateBudget(context.Background(), func(b *requestBudget) error {
+		if b.Hosts[host] > 0 {
+			b.Hosts[host]--
+		}
+		return nil
+	})
+}
+
+// updateBudget applies change to the current budget and stores it unless
+// change fails
+func (l *RateLimiter) updateBudget(ctx context.Context, change func(*requestBudget) error) error {
+	if l.options.BudgetFile == "" {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.loadBudget()
+		return change(&l.budget)
+	}
+	// the file lock is taken first, other requests are not held up by
+	// l.mu while it is waited for
+	unlock, err := LockFileContext(ctx, l.options.BudgetFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loadBudget()
+	if err := change(&l.budget); err != nil {
+		return err
+	}
+	return writeJSONFile(l.options.BudgetFile, &l.budget)
+}
+
+func (l *RateLimiter) loadBudget() {
+	if l.options.BudgetFile != "" {
+		stored := requestBudget{}
+		if readJSONFile(l.options.BudgetFile, &stored) == nil {
+			l.budget = stored
+		}
+	}
+	today := time.Now().Format(budgetDayLayout)
+	if l.budget.Day != today || l.budget.Hosts == nil {
+		l.budget = requestBudget{Day: today, Hosts: map[string]int{}}
+	}
+}
@@ -0,0 +1,251 @@
+package source
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SelectedGenres returns Genre and Genres without duplicates
+func (qo *QueryOptions) SelectedGenres() []string {
+	return uniqueFold(append([]string{qo.Genre}, qo.Genres...))
+}
+
+// SelectedAuthors returns Author and Authors without duplicates
+func (qo *QueryOptions) SelectedAuthors() []string {
+	return uniqueFold(append([]string{qo.Author}, qo.Authors...))
+}
+
+// IsMultiSelection tells whether the options select several genres or
+// authors, or exclude some, which a single source query cannot express
+func (qo *QueryOptions) IsMultiSelection() bool {
+	return len(qo.SelectedGenres()) > 1 || len(qo.SelectedAuthors()) > 1 ||
+		len(qo.ExcludeGenres) > 0 || len(qo.ExcludeAuthors) > 0
+}
+
+// Single moves a lone entry of Genres and Authors to Genre and Author,
+// for sources that only read those
+func (qo *QueryOptions) Single() QueryOptions {
+	single := *qo
+	if genres := qo.SelectedGenres(); len(genres) == 1 {
+		single.Genre, single.Genres = genres[0], nil
+	}
+	if authors := qo.SelectedAuthors(); len(authors) == 1 {
+		single.Author, single.Authors = authors[0], nil
+	}
+	return single
+}
+
+// Selects tells whether the options narrow down the quotes at all
+func (qo *QueryOptions) Selects() bool {
+	return len(qo.SelectedGenres()) > 0 || len(qo.SelectedAuthors()) > 0 || qo.Query != "" ||
+		len(qo.ExcludeGenres) > 0 || len(qo.ExcludeAuthors) > 0 || qo.HasConstraints() || qo.HasAnnotationFilter()
+}
+
+// Selection describes the genres and authors for display
+func (qo *QueryOptions) Selection() []string {
+	var out []string
+	if genres := qo.SelectedGenres(); len(genres) > 0 {
+		out = append(out, strings.Join(genres, " or "))
+	}
+	if authors := qo.SelectedAuthors(); len(authors) > 0 {
+		out = append(out, strings.Join(authors, " or "))
+	}
+	if excluded := uniqueFold(append(append([]string{}, qo.ExcludeGenres...), qo.ExcludeAuthors...)); len(excluded) > 0 {
+		out = append(out, "not "+strings.Join(excluded, ", "))
+	}
+	return out
+}
+
+func matchesSelection(q *Quote, qo *QueryOptions) bool {
+	if authors := qo.SelectedAuthors(); len(authors) > 0 && !containsFold(authors, q.Author) {
+		return false
+	}
+	if genres := qo.SelectedGenres(); len(genres) > 0 && !hasAnyGenre(q, genres) {
+		return false
+	}
+	return !excluded(q, qo)
+}
+
+func excluded(q *Quote, qo *QueryOptions) bool {
+	return containsFold(qo.ExcludeAuthors, q.Author) || hasAnyGenre(q, qo.ExcludeGenres)
+}
+
+func hasAnyGenre(q *Quote, genres []string) bool {
+	for _, g := range genres {
+		if q.HasGenre(g) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueFold drops empty values and case-insensitive duplicates, keeping the order
+func uniqueFold(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" && !containsFold(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// sameQuery compares options, which hold lists and cannot use ==
+func sameQuery(a, b QueryOptions) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// Selecting is implemented by sources that apply several genres, authors
+// and exclusions themselves
+type Selecting interface {
+	SelectsMany() bool
+}
+
+// Merged adds multi-selection to a source that takes a single genre and
+// author: it queries every genre and author pair, and merges the results
+// page by page into one stream without duplicates and excluded quotes.
+type Merged struct {
+	Sources
+
+	mu       sync.Mutex
+	query    QueryOptions
+	branches []*branch
+	seen     map[string]bool
+	quotes   []*Quote
+	// truncated is set when a branch had more pages than were read
+	truncated bool
+}
+
+// branch is one single-selection query of a Merged source
+type branch struct {
+	options QueryOptions
+	// next page to read, 0 once it is exhausted
+	next int32
+}
+
+// NewMerged creates a Merged object
+func NewMerged(src Sources) *Merged {
+	return &Merged{Sources: src}
+}
+
+func (m *Merged) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	if !options.IsMultiSelection() {
+		single := options.Single()
+		return m.Sources.Quotes(ctx, &single)
+	}
+	if s, ok := m.Sources.(Selecting); ok && s.SelectsMany() {
+		return m.Sources.Quotes(ctx, options)
+	}
+
+	page, limit := pageAndLimit(options)
+	query := *options
+	query.Page, query.Limit = 0, 0
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.branches == nil || !sameQuery(m.query, query) {
+		m.query, m.branches = query, m.split(query)
+		m.seen, m.quotes, m.truncated = map[string]bool{}, []*Quote{}, false
+	}
+
+	// one page of every branch in turn, until the page is full or all are exhausted
+	for len(m.quotes) < page*limit && m.pending() {
+		for _, b := range m.branches {
+			if b.next == 0 {
+				continue
+			}
+			opt := b.options
+			opt.Page, opt.Limit = b.next, DEFAULT_MIRROR_PAGE_SIZE
+			quotes, pag, err := m.Sources.Quotes(ctx, &opt)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, q := range quotes {
+				key := q.ID
+				if key == "" {
+					key = strings.ToLower(q.Author + "\x00" + q.Text)
+				}
+				if m.seen[key] || excluded(q, &query) {
+					continue
+				}
+				m.seen[key] = true
+				m.quotes = append(m.quotes, q)
+			}
+			b.next++
+			if pag != nil && pag.Truncated {
+				m.truncated = true
+			}
+			if len(quotes) == 0 || pag == nil || int(opt.Page) >= pag.TotalPages {
+				b.next = 0
+			} else if opt.Page >= FILTER_MAX_PAGES {
+				// e.g. a selection only excluding authors reads the whole source
+				b.next, m.truncated = 0, true
+			}
+		}
+	}
+
+	return streamPage(m.quotes, !m.pending(), m.truncated, options)
+}
+
+// split turns the selection into one query per genre and author pair
+func (m *Merged) split(query QueryOptions) []*branch {
+	genres, authors := query.SelectedGenres(), query.SelectedAuthors()
+	if len(genres) == 0 {
+		genres = []string{""}
+	}
+	if len(authors) == 0 {
+		authors = []string{""}
+	}
+	single := query
+	single.Genres, single.Authors = nil, nil
+	single.ExcludeGenres, single.ExcludeAuthors = nil, nil
+
+	var branches []*branch
+	for _, g := range genres {
+		for _, a := range authors {
+			opt := single
+			opt.Genre, opt.Author = g, a
+			branches = append(branches, &branch{options: opt, next: 1})
+		}
+	}
+	return branches
+}
+
+func (m *Merged) pending() bool {
+	for _, b := range m.branches {
+		if b.next != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SortsBy is forwarded when the source selects by itself, merged
+// streams are sorted by Sorted
+func (m *Merged) SortsBy(key string) bool {
+	if s, ok := m.Sources.(Selecting); !ok || !s.SelectsMany() {
+		return false
+	}
+	sorting, ok := m.Sources.(Sorting)
+	return ok && sorting.SortsBy(key)
+}
+
+// AppliesConstraints is forwarded when the source selects by itself
+func (m *Merged) AppliesConstraints() bool {
+	if s, ok := m.Sources.(Selecting); !ok || !s.SelectsMany() {
+		return false
+	}
+	c, ok := m.Sources.(Constraining)
+	return ok && c.AppliesConstraints()
+}
@@ -0,0 +1,142 @@
+package source
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	SIGNATURE_ALGORITHM = "ed25519"
+	BUNDLE_SIGNATURE    = "manifest.sig"
+)
+
+// Signature signs the manifest of a bundle, which holds the checksums
+// of the collections
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId"`
+	PublicKey string `json:"publicKey"`
+	Signature string `json:"signature"`
+}
+
+// KeyID is a short fingerprint of a public key
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// EncodePublicKey writes a public key as base64, the form kept in trusted keys
+func EncodePublicKey(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+// DecodePublicKey reads a public key as written by EncodePublicKey or as a PEM block
+func DecodePublicKey(s string) (ed25519.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("not an %s key", SIGNATURE_ALGORITHM)
+		}
+		return pub, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("not an %s public key", SIGNATURE_ALGORITHM)
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// sign signs data with key
+func sign(key ed25519.PrivateKey, data []byte) *Signature {
+	pub := key.Public().(ed25519.PublicKey)
+	return &Signature{
+		Algorithm: SIGNATURE_ALGORITHM,
+		KeyID:     KeyID(pub),
+		PublicKey: EncodePublicKey(pub),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}
+}
+
+// verify checks that the signature matches data
+func (s *Signature) verify(data []byte) error {
+	if s.Algorithm != SIGNATURE_ALGORITHM {
+		return fmt.Errorf("unknown signature algorithm %q", s.Algorithm)
+	}
+	pub, err := DecodePublicKey(s.PublicKey)
+	if err != nil {
+		return err
+	}
+	if KeyID(pub) != s.KeyID {
+		return fmt.Errorf("key id %s does not match the key", s.KeyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil || !ed25519.Verify(pub, data, sig) {
+		return fmt.Errorf("signature does not match the manifest")
+	}
+	return nil
+}
+
+// GenerateKey creates a key pair and writes the private key to path and
+// the public key to path.pub, both PEM encoded
+func GenerateKey(path string) (ed25519.PublicKey, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s exists", path)
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// LoadPrivateKey reads a private key written by GenerateKey
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an %s key", path, SIGNATURE_ALGORITHM)
+	}
+	return priv, nil
+}
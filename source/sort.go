@@ -0,0 +1,127 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	SORT_AUTHOR = "author"
+	SORT_GENRE  = "genre"
+	SORT_LENGTH = "length"
+	SORT_TEXT   = "text"
+	// SORT_RANDOM shuffles by Seed, the same seed gives the same order
+	SORT_RANDOM = "random"
+
+	// at most this many quotes are fetched to sort a source locally, the
+	// pages of a larger selection are marked as truncated
+	SORT_MAX_QUOTES = 5000
+)
+
+var SortKeys = []string{SORT_AUTHOR, SORT_GENRE, SORT_LENGTH, SORT_TEXT, SORT_RANDOM}
+
+// ValidSort checks a sort key, the empty key keeps the source order
+func ValidSort(key string) error {
+	if key == "" {
+		return nil
+	}
+	for _, k := range SortKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown sort %q, expected one of %s", key, strings.Join(SortKeys, ", "))
+}
+
+// SortQuotes orders quotes in place by key, ties keep their order
+func SortQuotes(quotes []*Quote, key string, seed int64) {
+	var less func(a, b *Quote) bool
+	switch key {
+	case SORT_AUTHOR:
+		less = func(a, b *Quote) bool { return strings.ToLower(a.Author) < strings.ToLower(b.Author) }
+	case SORT_GENRE:
+		less = func(a, b *Quote) bool { return strings.ToLower(a.Genre) < strings.ToLower(b.Genre) }
+	case SORT_LENGTH:
+		less = func(a, b *Quote) bool { return utf8.RuneCountInString(a.Text) < utf8.RuneCountInString(b.Text) }
+	case SORT_TEXT:
+		less = func(a, b *Quote) bool { return strings.ToLower(a.Text) < strings.ToLower(b.Text) }
+	case SORT_RANDOM:
+		less = func(a, b *Quote) bool { return shuffleRank(a, seed) < shuffleRank(b, seed) }
+	default:
+		return
+	}
+	sort.SliceStable(quotes, func(i, j int) bool { return less(quotes[i], quotes[j]) })
+}
+
+// shuffleRank hashes the quote with the seed, so the order survives paging
+func shuffleRank(q *Quote, seed int64) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d\x00%s\x00%s", seed, q.ID, q.Text)
+	return h.Sum64()
+}
+
+// Sorting is implemented by sources that apply QueryOptions.Sort themselves
+type Sorting interface {
+	SortsBy(key string) bool
+}
+
+// Sorted adds sorting to a source that cannot sort: the matching quotes
+// are fetched page by page, merged, sorted and paged through locally.
+// The quotes of the last query are kept so paging does not fetch again.
+type Sorted struct {
+	Sources
+
+	mu     sync.Mutex
+	loaded bool
+	query  QueryOptions
+	quotes []*Quote
+	// truncated is set when more quotes matched than were sorted
+	truncated bool
+}
+
+// NewSorted creates a Sorted object
+func NewSorted(src Sources) *Sorted {
+	return &Sorted{Sources: src}
+}
+
+func (s *Sorted) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	if options.Sort == "" {
+		return s.Sources.Quotes(ctx, options)
+	}
+	if sorting, ok := s.Sources.(Sorting); ok && sorting.SortsBy(options.Sort) {
+		return s.Sources.Quotes(ctx, options)
+	}
+	if err := ValidSort(options.Sort); err != nil {
+		return nil, nil, err
+	}
+
+	query := *options
+	query.Page, query.Limit = 0, 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded || !sameQuery(s.query, query) {
+		unsorted := query
+		unsorted.Sort, unsorted.Seed = "", 0
+		unsorted.Limit = DEFAULT_MIRROR_PAGE_SIZE
+		// one more tells whether the selection is larger than the cap
+		quotes, truncated, err := CollectQuotes(ctx, s.Sources, &unsorted, SORT_MAX_QUOTES+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(quotes) > SORT_MAX_QUOTES {
+			quotes, truncated = quotes[:SORT_MAX_QUOTES], true
+		}
+		SortQuotes(quotes, query.Sort, query.Seed)
+		s.loaded, s.query, s.quotes, s.truncated = true, query, quotes, truncated
+	}
+	quotes, pag, err := paginateQuotes(s.quotes, options)
+	if pag != nil {
+		pag.Truncated = s.truncated
+	}
+	return quotes, pag, err
+}
@@ -11,3 +11,42 @@ type Sources interface {
 	Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error)
 	PrintQuotesPage(title string, quotes []*Quote, columns int)
 }
+
+type quietKey struct{}
+
+// Quietly marks ctx as belonging to background requests,
+// sources give no interactive feedback for them
+func Quietly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quietKey{}, true)
+}
+
+func isQuiet(ctx context.Context) bool {
+	quiet, _ := ctx.Value(quietKey{}).(bool)
+	return quiet
+}
+
+// CollectQuotes pages through a source until all quotes matching options,
+// or at most max of them when max > 0, are fetched. truncated tells that
+// the source stopped reading early, so more quotes may match.
+func CollectQuotes(ctx context.Context, src Sources, options *QueryOptions, max int) (quotes []*Quote, truncated bool, err error) {
+	opt := *options
+	if opt.Page <= 0 {
+		opt.Page = 1
+	}
+	var all []*Quote
+	for {
+		quotes, pag, err := src.Quotes(ctx, &opt)
+		if err != nil {
+			return nil, false, err
+		}
+		all = append(all, quotes...)
+		truncated = truncated || pag != nil && pag.Truncated
+		if max > 0 && len(all) >= max {
+			return all[:max], truncated, nil
+		}
+		if len(quotes) == 0 || pag == nil || int(opt.Page) >= pag.TotalPages {
+			return all, truncated, nil
+		}
+		opt.Page++
+	}
+}
@@ -0,0 +1,87 @@
+package source
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Static is a Sources over quotes held in memory
+type Static struct {
+	quotes []*Quote
+}
+
+// NewStatic creates a Static source, quotes keep their order
+func NewStatic(quotes []*Quote) *Static {
+	return &Static{quotes: quotes}
+}
+
+func (s *Static) RandomQuote(ctx context.Context) (*Quote, error) {
+	if len(s.quotes) == 0 {
+		return nil, ErrQuoteNotFound
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return s.quotes[r.Intn(len(s.quotes))], nil
+}
+
+func (s *Static) AllGenres(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	var genres []string
+	for _, q := range s.quotes {
+		for _, g := range q.Genres() {
+			if !seen[g] {
+				seen[g] = true
+				genres = append(genres, g)
+			}
+		}
+	}
+	sort.Strings(genres)
+	return genres, nil
+}
+
+func (s *Static) AllAuthors(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	var authors []string
+	for _, q := range s.quotes {
+		if q.Author != "" && !seen[q.Author] {
+			seen[q.Author] = true
+			authors = append(authors, q.Author)
+		}
+	}
+	sort.Strings(authors)
+	return authors, nil
+}
+
+func (s *Static) Quotes(ctx context.Context, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	var matches []*Quote
+	for _, q := range s.quotes {
+		if matchesOptions(q, options) {
+			matches = append(matches, q)
+		}
+	}
+	SortQuotes(matches, options.Sort, options.Seed)
+	return paginateQuotes(matches, options)
+}
+
+func (s *Static) SelectsMany() bool {
+	return true
+}
+
+func (s *Static) AppliesConstraints() bool {
+	return true
+}
+
+func (s *Static) SortsBy(key string) bool {
+	return ValidSort(key) == nil
+}
+
+func (s *Static) PrintQuotesPage(title string, quotes []*Quote, columns int) {
+	PrintQuotesPage(title, quotes, columns)
+}
+
+// paginateQuotes pages through quotes already in memory
+func paginateQuotes(quotes []*Quote, options *QueryOptions) ([]*Quote, *Pagination, error) {
+	start, end, pag := pageBounds(len(quotes), options)
+	return quotes[start:end], pag, nil
+}